@@ -0,0 +1,87 @@
+package retry
+
+import (
+	"errors"
+	"time"
+)
+
+type outcome byte
+
+const (
+	// OutcomeSuccess - step succeeded.
+	OutcomeSuccess outcome = 0
+	// OutcomeExhausted - step gave up retrying.
+	OutcomeExhausted outcome = 1
+	// OutcomeFatal - step was stopped by fatal error.
+	OutcomeFatal outcome = 2
+	// OutcomeCanceled - step was stopped by context cancellation.
+	OutcomeCanceled outcome = 3
+	// OutcomeRejected - step was not run, or stopped for any other reason (i.e. open circuit).
+	OutcomeRejected outcome = 4
+)
+
+// String returns human-readable name of outcome.
+func (o outcome) String() string {
+	switch o {
+	case OutcomeExhausted:
+		return "exhausted"
+	case OutcomeFatal:
+		return "fatal"
+	case OutcomeCanceled:
+		return "canceled"
+	case OutcomeRejected:
+		return "rejected"
+	}
+
+	return "success"
+}
+
+// AuditRecord holds summary of single retry loop,
+// MaxBackoff holds longest single backoff, waited during loop.
+type AuditRecord struct {
+	Start      time.Time
+	End        time.Time
+	Err        error
+	Name       string
+	MaxBackoff time.Duration
+	Attempts   int
+	Outcome    outcome
+}
+
+func newAuditRecord(
+	name string,
+	start time.Time,
+	attempts int,
+	longest time.Duration,
+	canceled bool,
+	err error,
+) (rv AuditRecord) {
+	rv = AuditRecord{
+		Name:       name,
+		Start:      start,
+		End:        time.Now(),
+		Attempts:   attempts,
+		MaxBackoff: longest,
+		Err:        err,
+	}
+
+	var (
+		exh *ExhaustedError
+		fat *FatalError
+	)
+
+	switch {
+	case err == nil:
+		rv.Outcome = OutcomeSuccess
+	case errors.As(err, &exh):
+		rv.Outcome = OutcomeExhausted
+	case errors.As(err, &fat):
+		rv.Outcome = OutcomeFatal
+	case canceled:
+		rv.Outcome = OutcomeCanceled
+	default:
+		rv.Outcome = OutcomeRejected
+	}
+
+	return rv
+}
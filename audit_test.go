@@ -0,0 +1,140 @@
+package retry_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/s0rg/retry"
+)
+
+func TestAuditSink(t *testing.T) {
+	t.Parallel()
+
+	var records []retry.AuditRecord
+
+	try := retry.New(
+		retry.Count(maxRetries),
+		retry.Sleep(time.Millisecond),
+		retry.Fatal(errFatal),
+		retry.AuditSink(func(r retry.AuditRecord) {
+			records = append(records, r)
+		}),
+	)
+
+	fail := newFailer(errFail, func() {})
+
+	var table = []struct {
+		fn       func() error
+		errCount int
+		attempts int
+		outcome  string
+		failed   bool
+	}{
+		{fn: fail.Fail, errCount: 1, attempts: 2, outcome: "success"},
+		{fn: fail.Fail, errCount: maxTries, attempts: maxTries, outcome: "exhausted", failed: true},
+		{fn: func() error { return errFatal }, attempts: 1, outcome: "fatal", failed: true},
+	}
+
+	for n, s := range table {
+		fail.Reset(s.errCount)
+
+		start := time.Now()
+
+		err := try.Single("test-audit", s.fn)
+
+		if len(records) != n+1 {
+			t.Fatalf("step %d: records = %d (want: %d)", n, len(records), n+1)
+		}
+
+		r := records[n]
+
+		if r.Name != "test-audit" || r.Attempts != s.attempts || r.Outcome.String() != s.outcome {
+			t.Fatalf("step %d: unexpected record: %+v", n, r)
+		}
+
+		if !errors.Is(r.Err, err) || (r.Err != nil) != s.failed {
+			t.Fatalf("step %d: err = %v (want: %v)", n, r.Err, err)
+		}
+
+		if r.Start.Before(start) || r.End.Before(r.Start) {
+			t.Fatalf("step %d: unexpected times: %v - %v", n, r.Start, r.End)
+		}
+	}
+}
+
+func TestAuditMaxBackoff(t *testing.T) {
+	t.Parallel()
+
+	var rec retry.AuditRecord
+
+	try := retry.New(
+		retry.Count(3),
+		retry.Sleep(time.Millisecond),
+		retry.Mode(retry.Exponential),
+		retry.AuditSink(func(r retry.AuditRecord) { rec = r }),
+	)
+
+	_ = try.Single("test-max-backoff", func() error { return errFail })
+
+	// delays: 2ms, 4ms, 8ms
+	if want := 8 * time.Millisecond; rec.MaxBackoff != want {
+		t.Fatalf("max backoff = %s (want: %s)", rec.MaxBackoff, want)
+	}
+
+	_ = try.Single("test-max-backoff", func() error { return nil })
+
+	if rec.MaxBackoff != 0 {
+		t.Fatalf("max backoff = %s (want: 0)", rec.MaxBackoff)
+	}
+}
+
+func TestAuditOutcomes(t *testing.T) {
+	t.Parallel()
+
+	var rec retry.AuditRecord
+
+	try := retry.New(
+		retry.Count(maxRetries),
+		retry.Sleep(time.Millisecond),
+		retry.AuditSink(func(r retry.AuditRecord) { rec = r }),
+	)
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	fail := func() error { return errFail }
+
+	var table = []struct {
+		run     func() error
+		outcome string
+	}{
+		{run: func() error {
+			return try.Single("audit", func() error { return retry.Permanent(errFail) })
+		}, outcome: "fatal"},
+		{run: func() error { return try.SingleCtx(canceled, "audit", fail) }, outcome: "canceled"},
+		{run: func() error {
+			return try.With(retry.DelayFirst(true), retry.Sleep(time.Hour)).SingleCtx(canceled, "audit", fail)
+		}, outcome: "canceled"},
+		{run: func() error {
+			s := try.NewSession()
+			s.Cancel()
+
+			return s.Single("audit", fail)
+		}, outcome: "canceled"},
+		{run: func() error {
+			return try.With(retry.CircuitOpen(func() bool { return true })).Single("audit", fail)
+		}, outcome: "rejected"},
+	}
+
+	for n, s := range table {
+		if err := s.run(); err == nil {
+			t.Fatalf("step %d: no error", n)
+		}
+
+		if got := rec.Outcome.String(); got != s.outcome {
+			t.Fatalf("step %d: outcome = %q (want: %q)", n, got, s.outcome)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package retry
+
+import (
+	"sync"
+	"time"
+)
+
+// bucket is a simple token bucket, refilled in full once per window.
+type bucket struct {
+	last   time.Time
+	per    time.Duration
+	mu     sync.Mutex
+	max    int
+	tokens int
+}
+
+func newBucket(tokens int, per time.Duration) *bucket {
+	return &bucket{
+		max:    tokens,
+		tokens: tokens,
+		per:    per,
+		last:   time.Now(),
+	}
+}
+
+// valid reports whenever bucket can ever grant a token, and refills at all.
+func (b *bucket) valid() (ok bool) {
+	return b.max > 0 && b.per > 0
+}
+
+// take consumes single token, if any available.
+func (b *bucket) take() (ok bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if now := time.Now(); now.Sub(b.last) >= b.per {
+		b.tokens, b.last = b.max, now
+	}
+
+	if b.tokens <= 0 {
+		return false
+	}
+
+	b.tokens--
+
+	return true
+}
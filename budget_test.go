@@ -0,0 +1,61 @@
+package retry_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/s0rg/retry"
+)
+
+func TestRetryBudget(t *testing.T) {
+	t.Parallel()
+
+	const (
+		tokens = 3
+		window = 100 * time.Millisecond
+	)
+
+	var count int
+
+	try := retry.New(
+		retry.Count(maxRetries),
+		retry.Sleep(time.Millisecond),
+		retry.RetryBudget(tokens, window),
+	)
+
+	fail := func() error {
+		count++
+
+		return errFail
+	}
+
+	for i := 0; i < maxTries; i++ {
+		err := try.Single("test-budget", fail)
+		if !errors.Is(err, errFail) {
+			t.Fatalf("step %d: err == %v", i, err)
+		}
+
+		// first call has enough tokens, others run out of them.
+		if errors.Is(err, retry.ErrBudgetExhausted) != (i > 0) {
+			t.Fatalf("step %d: err == %v (budget)", i, err)
+		}
+	}
+
+	// first call: 3 attempts (2 tokens), second: 2 attempts (1 token), third: no tokens left.
+	if want := maxTries + 2 + 1; count != want {
+		t.Fatalf("count = %d (want: %d)", count, want)
+	}
+
+	time.Sleep(window)
+
+	count = 0
+
+	if err := try.Single("test-budget", fail); !errors.Is(err, errFail) {
+		t.Fatalf("err == %v", err)
+	}
+
+	if count != maxTries {
+		t.Fatalf("after refill: count = %d (want: %d)", count, maxTries)
+	}
+}
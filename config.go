@@ -1,10 +1,19 @@
 package retry
 
 import (
+	"cmp"
+	"context"
 	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"math"
+	"math/rand/v2"
+	"os"
+	"os/signal"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"golang.org/x/sync/errgroup"
@@ -13,7 +22,7 @@ import (
 type mode byte
 
 const (
-	// Simple mode - time increases by sleep + jitter*attempt.
+	// Simple mode - time increases by sleep + jitter*attempt, use `Constant` for flat delays.
 	Simple mode = 0
 	// Linear mode - time increases by sleep*attempt + jitter.
 	Linear mode = 1
@@ -21,12 +30,49 @@ const (
 	Exponential mode = 2
 	// Fibonacci mode - time increases by sleep*fibonacci(attempt) + jitter.
 	Fibonacci mode = 3
+	// FullJitter mode - AWS-style "full jitter": time is uniformly random in
+	// [0, min(MaxDelay, sleep*base^attempt)), `Jitter` and `RandomJitter` are ignored.
+	FullJitter mode = 4
+	// Decorrelated mode - "decorrelated jitter": time is uniformly random in
+	// [sleep, previous*3), capped by MaxDelay, `Jitter` and `RandomJitter` are ignored.
+	Decorrelated mode = 5
+	// Constant mode - time is always sleep + jitter, regardless of attempt.
+	Constant mode = 6
 )
 
+// String returns human-readable name of mode.
+func (m mode) String() string {
+	switch m {
+	case Linear:
+		return "linear"
+	case Exponential:
+		return "exponential"
+	case Fibonacci:
+		return "fibonacci"
+	case FullJitter:
+		return "full-jitter"
+	case Decorrelated:
+		return "decorrelated"
+	case Constant:
+		return "constant"
+	}
+
+	return "simple"
+}
+
+// valid reports whenever mode is one of known ones.
+func (m mode) valid() bool {
+	return m <= Constant
+}
+
+// MaxErrorHistory limits number of distinct error messages tracked per call.
+const MaxErrorHistory = 64
+
 const (
 	minParallel = 0
 	minCount    = 1
 	two         = 2
+	maxShift    = 63
 	minSleep    = time.Second / 2
 	minDuration = time.Duration(0)
 )
@@ -35,22 +81,97 @@ const (
 type Step struct {
 	Func func() error
 	Name string
+	// Count overrides number of retries for this step, zero means `Config` default.
+	Count int
+	// Sleep overrides base sleep duration for this step, zero means `Config` default.
+	Sleep time.Duration
+	// Mode overrides backoff mode for this step, zero (`Simple`) means `Config` default.
+	Mode mode
+	// Priority sets order of steps in `ChainByPriority`, higher runs first.
+	Priority int
 }
 
-// Config holds configuration.
+// Report describes single finished call.
+type Report struct {
+	// Attempts is a number of calls made.
+	Attempts int
+	// TotalSleep is a total time spent in backoff.
+	TotalSleep time.Duration
+	// LastDelay is a last delay waited before attempt.
+	LastDelay time.Duration
+}
+
+// state holds internal loop state, reported to inspection hook (used by tests).
+type state struct {
+	Err     error
+	Delay   time.Duration
+	Attempt int
+}
+
+// Config holds configuration, it is safe for concurrent use after construction: all state
+// of retry loop is kept per call, shared parts (random source, budget, stats) are synchronized.
 type Config struct {
+	deadline    time.Time
+	budget      *bucket
+	rnd         *source
+	stats       *counters
+	metrics     Metrics
+	logger      Logger
+	slog        *slog.Logger
+	events      *eventLog
+	audit       func(AuditRecord)
+	classify    func(error) string
+	nameFormat  func(string, string) string
+	onShared    func() error
+	inspect     func(state)
+	countFn     func() int
+	remaining   func() int
+	circuit     func() bool
+	freeIf      func(error) bool
+	retryIf     func(error) bool
+	fatalIf     func(error) bool
+	successIf   func(error) bool
+	delayFrom   func(error) (time.Duration, bool)
+	onRecovery  func(string, int)
+	onGiveUp    func(string, int, error)
+	onFatal     func(string, int, error)
+	onCancel    func()
+	onStart     func(string, time.Duration, int)
+	backoff     func(int) time.Duration
+	yield       chan<- struct{}
+	drain       <-chan struct{}
+	sem         chan struct{}
 	fatal       []error
 	sleep       time.Duration
+	freshness   time.Duration
+	maxDelay    time.Duration
+	minDelay    time.Duration
+	maxElapsed  time.Duration
+	randJitter  time.Duration
 	jitter      time.Duration
+	downJitter  time.Duration
 	count       int
+	distinct    int
+	maxFree     int
+	seqBelow    int
+	warnAbove   int
 	parallelism int
+	base        float64
+	jitterFrac  float64
+	spread      float64
 	mode        mode
 	verbose     bool
+	shared      bool
+	recover     bool
+	logSchedule bool
+	collect     bool
+	delayFirst  bool
+	jitterDown  bool
 }
 
 // New creates new `Config` with given options
 // If no options given default configuration will
-// be applied: 1 retry in 1 second.
+// be applied: single attempt, without retries.
 func New(opts ...option) (c *Config) {
 	c = &Config{}
 
@@ -63,70 +184,474 @@ func New(opts ...option) (c *Config) {
 	return c
 }
 
-// Single executes 'fn', until no error returned, at most `Count` times (default is 1,
-// so `fn` will be executed at most 2 times), each execution delayed on time given
-// as `Sleep` option (default is 1 second).
+// Single executes 'fn', until no error returned, at most `Count`+1 times (`Count` is number
+// of retries, by default 0, so `fn` will be executed exactly once), each re-try delayed on time
+// given as `Sleep` option (default is half a second). There is no delay after last attempt.
 func (c *Config) Single(name string, fn func() error) (err error) {
-	for n := 0; n < c.count; n++ {
-		if err = fn(); err == nil {
-			return nil
-		}
+	return c.SingleCtx(context.Background(), name, fn)
+}
 
-		if c.isFatal(err) {
-			break
-		}
+// SingleCtx acts like `Single`, but aborts as soon as `ctx` is done, returning its error,
+// `fn` is not invoked at all, if `ctx` is already done.
+func (c *Config) SingleCtx(ctx context.Context, name string, fn func() error) (err error) {
+	_, err = c.single(ctx, name, fn)
 
-		if c.verbose {
-			log.Printf("step %s:%d err: %v", name, n, err)
-		}
+	return err
+}
 
-		if n < c.count {
-			time.Sleep(c.stepDuration(n + 1))
-		}
+// SingleN acts like `Single`, but also reports number of `fn` invocations made.
+func (c *Config) SingleN(name string, fn func() error) (attempts int, err error) {
+	rep, err := c.single(context.Background(), name, fn)
+
+	return rep.Attempts, err
+}
+
+// SingleReport acts like `Single`, but also returns report of call.
+func (c *Config) SingleReport(name string, fn func() error) (rep Report, err error) {
+	return c.single(context.Background(), name, fn)
+}
+
+// SingleWith acts like `Single`, but applies given options on top of config for this call
+// only, shared config stays untouched. `Fatal` errors given here are merged with configured.
+func (c *Config) SingleWith(name string, fn func() error, opts ...option) (err error) {
+	return c.clone(opts...).Single(name, fn)
+}
+
+// With returns copy of config with given options applied on top, i.e. to derive variants
+// from common base, original stays untouched. Copy has its own `Stats` and `SharedLimit`,
+// but shares `RetryBudget` with original, unless new one is given.
+func (c *Config) With(opts ...option) (rv *Config) {
+	rv = c.clone()
+	rv.stats, rv.sem = nil, nil
+
+	for _, o := range opts {
+		o(rv)
 	}
 
-	return fmt.Errorf("%s: %w", name, err)
+	rv.validate()
+
+	return rv
+}
+
+// Wrap returns retrying version of `fn`, every call of result runs its own retry loop.
+func (c *Config) Wrap(name string, fn func() error) func() error {
+	return func() error {
+		return c.Single(name, fn)
+	}
 }
 
 // Chain executes several `steps` one by one, returning first error.
 func (c *Config) Chain(steps ...Step) (err error) {
+	return c.ChainCtx(context.Background(), steps...)
+}
+
+// ChainCtx acts like `Chain`, but aborts as soon as `ctx` is done, returning its error,
+// no more steps are started after that.
+func (c *Config) ChainCtx(ctx context.Context, steps ...Step) (err error) {
 	var step *Step
 
 	for i := 0; i < len(steps); i++ {
 		step = &steps[i]
 
-		if err = c.Single(step.Name, step.Func); err != nil {
-			return fmt.Errorf("chain: %w", err)
+		if err = c.forStep(step).SingleCtx(ctx, c.compose("chain", step.Name), step.Func); err != nil {
+			return err
 		}
 	}
 
 	return nil
 }
 
-// Parallel executes several `steps` in parallel.
+// ChainByPriority acts like `Chain`, but runs `steps` in descending order of their priority,
+// steps with same priority keep their order.
+func (c *Config) ChainByPriority(steps ...Step) (err error) {
+	sorted := slices.Clone(steps)
+
+	slices.SortStableFunc(sorted, func(a, b Step) int {
+		return cmp.Compare(b.Priority, a.Priority)
+	})
+
+	return c.Chain(sorted...)
+}
+
+// Fallback executes several `steps` one by one, until first of them succeeds,
+// if all of them fails - returns all errors joined.
+func (c *Config) Fallback(steps ...Step) (err error) {
+	var (
+		step *Step
+		errs []error
+	)
+
+	for i := 0; i < len(steps); i++ {
+		step = &steps[i]
+
+		if err = c.forStep(step).Single(c.compose("fallback", step.Name), step.Func); err == nil {
+			return nil
+		}
+
+		errs = append(errs, err)
+	}
+
+	return errors.Join(errs...)
+}
+
+// Parallel executes several `steps` in parallel, returning first error.
+// With `RecoverPanics` enabled, panic in step is reported as error of that step.
 func (c *Config) Parallel(steps ...Step) (err error) {
+	return c.fanOut(context.Background(), steps)
+}
+
+// ParallelCtx acts like `Parallel`, but aborts as soon as `ctx` is done, also first
+// failed step (for any reason: fatal error, exhausted attempts, etc.) cancels the others,
+// at their next backoff.
+func (c *Config) ParallelCtx(ctx context.Context, steps ...Step) (err error) {
+	if len(steps) < c.seqBelow {
+		return c.sequential(ctx, steps, nil, true)
+	}
+
+	eg, gctx := errgroup.WithContext(ctx)
+
+	return c.parallel(gctx, eg, steps, nil)
+}
+
+// ParallelSignal returns function, that acts like `ParallelCtx`, with context canceled, as
+// soon as process receives any of `sig` (i.e. `os.Interrupt`), so steps abort their backoff.
+func (c *Config) ParallelSignal(sig ...os.Signal) func(steps ...Step) error {
+	return c.parallelNotify(signal.NotifyContext, sig...)
+}
+
+// notifyFunc creates context, canceled on given signals, as `signal.NotifyContext` does.
+type notifyFunc func(context.Context, ...os.Signal) (context.Context, context.CancelFunc)
+
+func (c *Config) parallelNotify(notify notifyFunc, sig ...os.Signal) func(steps ...Step) error {
+	return func(steps ...Step) error {
+		ctx, stop := notify(context.Background(), sig...)
+		defer stop()
+
+		return c.ParallelCtx(ctx, steps...)
+	}
+}
+
+// ParallelAll acts like `Parallel`, but awaits all steps, returning errors of all failed
+// steps joined.
+func (c *Config) ParallelAll(steps ...Step) (err error) {
+	return errors.Join(c.all(steps)...)
+}
+
+// ParallelMap acts like `ParallelAll`, but returns final error of every step (nil for
+// succeeded ones) by its name. Duplicate names are suffixed with "#n", where `n` is
+// number of occurrence, starting from 2, i.e. "db", "db#2", "db#3".
+func (c *Config) ParallelMap(steps ...Step) (rv map[string]error) {
+	errs := c.all(steps)
+
+	rv = make(map[string]error, len(steps))
+
+	for i := 0; i < len(steps); i++ {
+		name := steps[i].Name
+
+		for n := two; ; n++ {
+			if _, ok := rv[name]; !ok {
+				break
+			}
+
+			name = steps[i].Name + "#" + strconv.Itoa(n)
+		}
+
+		rv[name] = errs[i]
+	}
+
+	return rv
+}
+
+// Any executes several `steps` in parallel, returning name of first succeeded one as `winner`,
+// the others are cancelled at their next backoff, in background. If all of them fails -
+// returns empty `winner` and all errors joined.
+func (c *Config) Any(ctx context.Context, steps ...Step) (winner string, err error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		name string
+		err  error
+	}
+
+	results := make(chan result, len(steps))
+
+	for i := 0; i < len(steps); i++ {
+		step := &steps[i]
+
+		go func() {
+			results <- result{
+				name: step.Name,
+				err:  c.forStep(step).SingleCtx(ctx, c.compose("any", step.Name), step.Func),
+			}
+		}()
+	}
+
+	errs := make([]error, 0, len(steps))
+
+	for i := 0; i < len(steps); i++ {
+		res := <-results
+		if res.err == nil {
+			return res.name, nil
+		}
+
+		errs = append(errs, res.err)
+	}
+
+	return "", errors.Join(errs...)
+}
+
+// BatchSingle executes all `fns` in order, each round, retrying whole batch in lockstep,
+// with shared backoff timing, until all of them succeeds in same round.
+func (c *Config) BatchSingle(names []string, fns []func() error) (err error) {
+	if len(names) != len(fns) {
+		return ErrBatchMismatch
+	}
+
+	return c.Single("batch", func() error {
+		var errs []error
+
+		for i := 0; i < len(fns); i++ {
+			if err := fns[i](); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", names[i], err))
+			}
+		}
+
+		return errors.Join(errs...)
+	})
+}
+
+// fanOut runs all `steps` in parallel, returning first error, `ctx` stops them all,
+// but unlike `ParallelCtx`, failed step does not cancel the others.
+func (c *Config) fanOut(ctx context.Context, steps []Step) (err error) {
+	if len(steps) < c.seqBelow {
+		return c.sequential(ctx, steps, nil, false)
+	}
+
 	var eg errgroup.Group
 
+	return c.parallel(ctx, &eg, steps, nil)
+}
+
+// parallel runs all `steps` in group `eg`, if `errs` given - errors of steps are stored
+// there, by index, instead of failing group.
+func (c *Config) parallel(ctx context.Context, eg *errgroup.Group, steps []Step, errs []error) (err error) {
+	var once sync.Once
+
 	if c.parallelism > 0 {
 		eg.SetLimit(c.parallelism)
 	}
 
 	for i := 0; i < len(steps); i++ {
-		step := steps[i]
+		step := &steps[i]
+
+		eg.Go(func() (serr error) {
+			serr = c.runStep(ctx, &once, step)
+			if errs != nil {
+				errs[i], serr = serr, nil
+			}
 
-		eg.Go(func() error {
-			return c.Single(step.Name, step.Func)
+			return serr
 		})
 	}
 
-	if err = eg.Wait(); err != nil {
-		return fmt.Errorf("parallel: %w", err)
+	return eg.Wait()
+}
+
+// sequential runs all `steps` one by one, with same semantics as `parallel`: if `errs` given -
+// errors of steps are stored there, by index, otherwise first error is returned, after all
+// steps run, or right away, if `stop` is set (as context of errgroup cancels the rest).
+func (c *Config) sequential(ctx context.Context, steps []Step, errs []error, stop bool) (err error) {
+	var once sync.Once
+
+	for i := 0; i < len(steps); i++ {
+		serr := c.runStep(ctx, &once, &steps[i])
+
+		switch {
+		case errs != nil:
+			errs[i] = serr
+		case serr == nil:
+		case stop:
+			return serr
+		case err == nil:
+			err = serr
+		}
 	}
 
-	return nil
+	return err
+}
+
+// all runs all `steps`, returning their errors, by index.
+func (c *Config) all(steps []Step) (errs []error) {
+	errs = make([]error, len(steps))
+
+	if len(steps) < c.seqBelow {
+		_ = c.sequential(context.Background(), steps, errs, false)
+
+		return errs
+	}
+
+	var eg errgroup.Group
+
+	_ = c.parallel(context.Background(), &eg, steps, errs)
+
+	return errs
+}
+
+// runStep runs single step of parallel group, under shared limit, if any.
+func (c *Config) runStep(ctx context.Context, once *sync.Once, step *Step) (err error) {
+	if c.sem != nil {
+		c.sem <- struct{}{}
+		defer func() { <-c.sem }()
+	}
+
+	return c.forStep(step).SingleCtx(ctx, c.compose("parallel", step.Name), c.shareFailure(once, step.Func))
+}
+
+// Schedule returns delays before each re-try, as `Single` would wait them, without sleeping,
+// it has `Count` values, one per re-try. For randomized modes and jitter options, values are
+// upper bounds, random source is not used, so seeded delays stay reproducible.
+func (c *Config) Schedule() []time.Duration {
+	return c.maxSchedule(c.count)
+}
+
+// Describe returns human-readable summary of configuration and its computed schedule,
+// suitable for logs and error messages. For randomized modes and jitter options, schedule
+// holds (exclusive) upper bounds of delays.
+func (c *Config) Describe() string {
+	return fmt.Sprintf("%s backoff, base=%s, min=%s, max=%s, count=%d, jitter=%s; schedule≈%s",
+		c.mode, c.sleep, limit(c.minDelay), limit(c.maxDelay), c.count-1, c.jitterKind(),
+		formatSchedule(c.schedule(c.count, supremum)))
+}
+
+// jitterKind returns human-readable summary of effective jitter options.
+func (c *Config) jitterKind() string {
+	var kinds []string
+
+	switch c.mode {
+	case FullJitter:
+		kinds = append(kinds, "full")
+	case Decorrelated:
+		kinds = append(kinds, "decorrelated")
+	default:
+		if c.jitter > 0 {
+			kinds = append(kinds, c.jitter.String())
+		}
+
+		if c.downJitter > 0 {
+			kinds = append(kinds, "down("+c.downJitter.String()+")")
+		}
+
+		if c.jitterFrac > 0 {
+			kinds = append(kinds, fmt.Sprintf("fraction(%g)", c.jitterFrac))
+		}
+
+		if c.spread > 0 {
+			kinds = append(kinds, fmt.Sprintf("spread(%g)", c.spread))
+		}
+
+		if c.randJitter > 0 {
+			kinds = append(kinds, "random("+c.randJitter.String()+")")
+		}
+	}
+
+	if len(kinds) == 0 {
+		return "none"
+	}
+
+	return strings.Join(kinds, "+")
+}
+
+// limit formats delay limit, zero means no limit.
+func limit(d time.Duration) string {
+	if d <= 0 {
+		return "none"
+	}
+
+	return d.String()
+}
+
+// shareFailure wraps `fn`, to run `OnSharedFailure` hook once per group, on first failure,
+// other steps failed meanwhile are blocked, until hook completes.
+func (c *Config) shareFailure(once *sync.Once, fn func() error) func() error {
+	if c.onShared == nil {
+		return fn
+	}
+
+	return func() (err error) {
+		if err = fn(); err != nil {
+			once.Do(func() {
+				if herr := c.onShared(); herr != nil && c.verbose {
+					c.logf("shared failure hook err: %v", herr)
+				}
+			})
+		}
+
+		return err
+	}
+}
+
+// compose builds full name of nested step.
+func (c *Config) compose(parent, step string) string {
+	if c.nameFormat != nil {
+		return c.nameFormat(parent, step)
+	}
+
+	return parent + ": " + step
+}
+
+// forStep returns config with overrides from `step` applied, `c` itself is never modified.
+func (c *Config) forStep(step *Step) (rv *Config) {
+	if step.Count <= 0 && step.Sleep <= 0 && step.Mode == Simple {
+		return c
+	}
+
+	rv = &Config{}
+	*rv = *c
+
+	if step.Count > 0 {
+		rv.count, rv.countFn = attempts(step.Count), nil
+	}
+
+	if step.Sleep > 0 {
+		rv.sleep = step.Sleep
+	}
+
+	if step.Mode != Simple && step.Mode.valid() {
+		rv.mode = step.Mode
+	}
+
+	return rv
+}
+
+func (c *Config) clone(opts ...option) (rv *Config) {
+	rv = &Config{}
+	*rv = *c
+
+	rv.fatal = slices.Clone(c.fatal)
+
+	for _, o := range opts {
+		o(rv)
+	}
+
+	rv.validate()
+
+	return rv
 }
 
 func (c *Config) validate() {
+	if !c.mode.valid() {
+		c.logf("retry: unknown mode %d, falling back to %s", c.mode, Simple)
+
+		c.mode = Simple
+	}
+
+	if c.budget != nil && !c.budget.valid() {
+		c.logf("retry: invalid budget %d per %s, ignoring", c.budget.max, c.budget.per)
+
+		c.budget = nil
+	}
+
 	if c.count < minCount {
 		c.count = minCount
 	}
@@ -139,42 +664,335 @@ func (c *Config) validate() {
 		c.jitter = minDuration
 	}
 
+	if c.base <= 1.0 {
+		c.base = two
+	}
+
+	if c.randJitter < minDuration {
+		c.randJitter = minDuration
+	}
+
+	if c.jitterFrac < 0 {
+		c.jitterFrac = 0
+	}
+
+	if c.spread < 0 {
+		c.spread = 0
+	}
+
+	switch {
+	case c.jitterDown && c.jitter > 0:
+		c.downJitter, c.jitter = c.jitter, minDuration
+	case !c.jitterDown && c.downJitter > 0:
+		c.jitter, c.downJitter = c.downJitter, minDuration
+	}
+
+	if c.jitterFrac > 0 {
+		c.jitter, c.downJitter = minDuration, minDuration
+	}
+
+	if c.stats == nil {
+		c.stats = &counters{}
+	}
+
+	if c.rnd == nil {
+		c.rnd = newSource(rand.Uint64()) //nolint:gosec // jitter is not security-sensitive
+	}
+
+	if c.maxDelay < minDuration {
+		c.maxDelay = minDuration
+	}
+
+	if c.minDelay < minDuration {
+		c.minDelay = minDuration
+	}
+
+	if c.maxDelay > 0 {
+		c.minDelay = min(c.minDelay, c.maxDelay)
+	}
+
 	if c.parallelism < minParallel {
 		c.parallelism = minParallel
 	}
+
+	if c.distinct < 0 {
+		c.distinct = 0
+	}
+
+	if c.distinct >= MaxErrorHistory {
+		c.distinct = MaxErrorHistory - 1
+	}
+
+	if c.maxFree < 0 {
+		c.maxFree = 0
+	}
+
+	if c.freeIf != nil && c.maxFree == 0 {
+		c.maxFree = c.count
+	}
+
+	if c.shared && c.parallelism > minParallel && c.sem == nil {
+		c.sem = make(chan struct{}, c.parallelism)
+	}
+}
+
+func (c *Config) exhausted(name string, err error) (rv *ExhaustedError) {
+	rv = &ExhaustedError{Name: name, Err: err}
+
+	if c.classify != nil {
+		rv.Category = c.classify(err)
+	}
+
+	return rv
 }
 
-func (c *Config) isFatal(err error) (yes bool) {
+// isFatal returns matched fatal error, if any, `err` itself is returned for `FatalIf` match.
+func (c *Config) isFatal(err error) (match error) {
 	for i := 0; i < len(c.fatal); i++ {
-		if yes = errors.Is(c.fatal[i], err); yes {
-			return true
+		if errors.Is(err, c.fatal[i]) {
+			return c.fatal[i]
 		}
 	}
 
-	return false
+	if c.fatalIf != nil && c.fatalIf(err) {
+		return err
+	}
+
+	return nil
 }
 
-func (c *Config) stepDuration(n int) (d time.Duration) {
+// stepDuration returns delay before attempt `n`, `prev` is previous delay (if any).
+func (c *Config) stepDuration(n int, prev time.Duration) (d time.Duration) {
+	return c.delay(n, prev, c.rnd.duration)
+}
+
+// delay computes delay before attempt `n`, `draw` returns random duration in [0, d),
+// for randomized parts.
+func (c *Config) delay(n int, prev time.Duration, draw func(time.Duration) time.Duration) (d time.Duration) {
+	jittered := false
+
+	if c.backoff != nil {
+		d = satAdd(max(c.backoff(n), 0), c.jitter)
+	} else {
+		d, jittered = c.modeDuration(n, prev, draw)
+	}
+
+	if c.jitterFrac > 0 && !jittered {
+		d = satAdd(d, draw(satFrac(d, c.jitterFrac)))
+	}
+
+	if c.spread > 0 && !jittered {
+		d = c.spreadJitter(d, draw)
+	}
+
+	if c.randJitter > 0 && !jittered {
+		d = satAdd(d, draw(c.randJitter))
+	}
+
+	if c.downJitter > 0 && !jittered {
+		d = max(d-c.downJitter+draw(c.downJitter), minDuration)
+	}
+
+	d = max(d, c.minDelay)
+
+	if c.maxDelay > 0 {
+		d = min(d, c.maxDelay)
+	}
+
+	return d
+}
+
+// modeDuration returns delay for configured mode, and whenever it is already randomized.
+func (c *Config) modeDuration(
+	n int,
+	prev time.Duration,
+	draw func(time.Duration) time.Duration,
+) (d time.Duration, jittered bool) {
 	switch c.mode {
 	case Linear:
-		return c.sleep*time.Duration(n) + c.jitter
+		d = satAdd(satMul(c.sleep, int64(n)), c.jitter)
 	case Exponential:
-		return c.sleep*time.Duration(ipow2(n)) + c.jitter
+		d = satAdd(c.exponential(n), c.jitter)
 	case Fibonacci:
-		return c.sleep*time.Duration(fibonacci(n)) + c.jitter
+		d = satAdd(satMul(c.sleep, fibonacci(n)), c.jitter)
+	case FullJitter:
+		return c.fullJitter(n, draw), true
+	case Decorrelated:
+		return c.decorrelated(prev, draw), true
+	case Constant:
+		d = satAdd(c.sleep, c.jitter)
+	default:
+		d = satAdd(c.sleep, satMul(c.jitter, int64(n)))
+	}
+
+	return d, false
+}
+
+// spreadJitter moves `d` by uniformly random value in [-d*spread, d*spread), not below zero.
+func (c *Config) spreadJitter(d time.Duration, draw func(time.Duration) time.Duration) time.Duration {
+	x := satFrac(d, c.spread)
+
+	v := draw(satMul(x, two))
+	if v < x {
+		return max(d-(x-v), minDuration)
+	}
+
+	return satAdd(d, v-x)
+}
+
+func (c *Config) exponential(n int) time.Duration {
+	if c.base == two {
+		return satMul(c.sleep, pow2(n))
+	}
+
+	if f := float64(c.sleep) * math.Pow(c.base, float64(n)); f < math.MaxInt64 {
+		return time.Duration(f)
+	}
+
+	return math.MaxInt64
+}
+
+func (c *Config) fullJitter(n int, draw func(time.Duration) time.Duration) (d time.Duration) {
+	d = c.exponential(n)
+
+	if c.maxDelay > 0 {
+		d = min(d, c.maxDelay)
+	}
+
+	return draw(d)
+}
+
+func (c *Config) decorrelated(prev time.Duration, draw func(time.Duration) time.Duration) (d time.Duration) {
+	prev = max(prev, c.sleep)
+
+	return c.sleep + draw(satMul(prev, 3)-c.sleep)
+}
+
+// attempts returns number of attempts for given number of retries, negative means none.
+func attempts(retries int) int {
+	return min(max(retries, 0), math.MaxInt-1) + 1
+}
+
+// maxSchedule returns worst-case delays before each re-try, for given number of attempts,
+// randomized parts are replaced by their upper bounds, so random source is never touched.
+func (c *Config) maxSchedule(count int) (rv []time.Duration) {
+	return c.schedule(count, upperBound)
+}
+
+// schedule returns delays before each re-try, for given number of attempts, with randomized
+// parts drawn by `draw`.
+func (c *Config) schedule(count int, draw func(time.Duration) time.Duration) (rv []time.Duration) {
+	var prev time.Duration
+
+	rv = make([]time.Duration, max(count-1, 0))
+
+	for n := 0; n < len(rv); n++ {
+		prev = c.delay(n+1, prev, draw)
+		rv[n] = prev
+	}
+
+	return rv
+}
+
+// upperBound returns largest value of random duration in [0, d).
+func upperBound(d time.Duration) time.Duration {
+	return max(d-1, minDuration)
+}
+
+// supremum returns exclusive upper bound of random duration in [0, d), as whole duration.
+func supremum(d time.Duration) time.Duration {
+	return d
+}
+
+func total(sched []time.Duration) (rv time.Duration) {
+	for _, d := range sched {
+		rv = satAdd(rv, d)
+	}
+
+	return rv
+}
+
+func tooDistinct(seen map[string]struct{}, err error, limit int) (yes bool) {
+	if len(seen) < MaxErrorHistory {
+		seen[err.Error()] = struct{}{}
 	}
 
-	return c.sleep + c.jitter*time.Duration(n)
+	return len(seen) > limit
 }
 
-func ipow2(v int) (rv int64) {
-	return int64(math.Pow(two, float64(v)))
+func budgetExhausted(last error) error {
+	if last == nil {
+		return ErrBudgetExhausted
+	}
+
+	return fmt.Errorf("%w: %w", ErrBudgetExhausted, last)
+}
+
+func formatSchedule(sched []time.Duration) string {
+	parts := make([]string, len(sched))
+
+	for i := 0; i < len(sched); i++ {
+		parts[i] = sched[i].String()
+	}
+
+	return "[" + strings.Join(parts, ",") + "]"
+}
+
+// pow2 returns 2^v, saturating to max int64 on overflow.
+func pow2(v int) (rv int64) {
+	if v >= maxShift {
+		return math.MaxInt64
+	}
+
+	return 1 << max(v, 0)
+}
+
+// fibs holds all fibonacci numbers, that fits into int64.
+var fibs = fibTable()
+
+func fibTable() (rv []int64) {
+	var cur, next int64 = 0, 1
+
+	for next <= math.MaxInt64-cur {
+		rv = append(rv, cur)
+		cur, next = next, cur+next
+	}
+
+	return append(rv, cur, next)
+}
+
+// fibonacci returns n-th fibonacci number, saturating to max int64 on overflow.
+func fibonacci(n int) (rv int64) {
+	if n >= len(fibs) {
+		return math.MaxInt64
+	}
+
+	return fibs[max(n, 0)]
+}
+
+// satMul multiplies duration by `k`, saturating to max duration on overflow.
+func satMul(d time.Duration, k int64) time.Duration {
+	if k != 0 && d > math.MaxInt64/time.Duration(k) {
+		return math.MaxInt64
+	}
+
+	return d * time.Duration(k)
+}
+
+// satFrac returns fraction `f` of non-negative duration, saturating to max duration on overflow.
+func satFrac(d time.Duration, f float64) time.Duration {
+	if v := float64(d) * f; v < math.MaxInt64 {
+		return time.Duration(v)
+	}
+
+	return math.MaxInt64
 }
 
-func fibonacci(n int) int64 {
-	if n < two {
-		return int64(n)
+// satAdd adds two non-negative durations, saturating to max duration on overflow.
+func satAdd(a, b time.Duration) time.Duration {
+	if a > math.MaxInt64-b {
+		return math.MaxInt64
 	}
 
-	return fibonacci(n-1) + fibonacci(n-two)
+	return a + b
 }
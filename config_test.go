@@ -1,14 +1,27 @@
 package retry_test
 
 import (
+	"bytes"
+	"context"
 	"errors"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/s0rg/retry"
 )
 
-const maxTries = 3
+const (
+	maxTries   = 3
+	maxRetries = maxTries - 1
+)
 
 var (
 	errFail  = errors.New("test fail")
@@ -74,7 +87,7 @@ func TestSingle(t *testing.T) {
 	fail := newFailer(errFail, func() { count++ })
 
 	try := retry.New(
-		retry.Count(maxTries),
+		retry.Count(maxRetries),
 		retry.Sleep(time.Millisecond),
 		retry.Mode(retry.Linear),
 	)
@@ -138,7 +151,7 @@ func TestChain(t *testing.T) {
 	fb := newFailer(errFail, func() { countB++ })
 
 	try := retry.New(
-		retry.Count(maxTries),
+		retry.Count(maxRetries),
 		retry.Sleep(time.Millisecond),
 		retry.Verbose(true),
 		retry.Mode(retry.Exponential),
@@ -213,7 +226,7 @@ func TestParallel(t *testing.T) {
 	fb := newFailer(errFail, func() { countB++ })
 
 	try := retry.New(
-		retry.Count(maxTries),
+		retry.Count(maxRetries),
 		retry.Sleep(time.Millisecond),
 		retry.Jitter(time.Millisecond),
 		retry.Parallelism(2),
@@ -245,6 +258,46 @@ func TestParallel(t *testing.T) {
 	}
 }
 
+func TestCount(t *testing.T) {
+	t.Parallel()
+
+	var table = []struct {
+		opt   func(*retry.Config)
+		execs int
+	}{
+		{opt: retry.Count(-1), execs: 1},
+		{opt: retry.Count(0), execs: 1},
+		{opt: retry.NoRetry(), execs: 1},
+		{opt: retry.Count(1), execs: 2},
+		{opt: retry.Count(maxRetries), execs: maxTries},
+	}
+
+	for n, s := range table {
+		var execs int
+
+		try := retry.New(
+			retry.Sleep(time.Millisecond),
+			s.opt,
+		)
+
+		if err := try.Single("test-count", func() error {
+			execs++
+
+			return errFail
+		}); !errors.Is(err, errFail) {
+			t.Fatalf("step %d: err == %v", n, err)
+		}
+
+		if execs != s.execs {
+			t.Fatalf("step %d: execs = %d (want: %d)", n, execs, s.execs)
+		}
+
+		if got := len(try.Schedule()); got != s.execs-1 {
+			t.Fatalf("step %d: schedule = %d (want: %d)", n, got, s.execs-1)
+		}
+	}
+}
+
 func TestValidate(t *testing.T) {
 	t.Parallel()
 
@@ -283,7 +336,7 @@ func TestFibonacci(t *testing.T) {
 	)
 
 	try := retry.New(
-		retry.Count(tryMax),
+		retry.Count(tryMax-1),
 		retry.Mode(retry.Fibonacci),
 	)
 
@@ -334,7 +387,7 @@ func TestFatal(t *testing.T) {
 	fb := newFailer(errFail, func() { countB++ })
 
 	try := retry.New(
-		retry.Count(maxTries),
+		retry.Count(maxRetries),
 		retry.Fatal(errFatal),
 		retry.Mode(retry.Fibonacci),
 	)
@@ -364,3 +417,2624 @@ func TestFatal(t *testing.T) {
 		countA, countB = 0, 0
 	}
 }
+
+func TestModeString(t *testing.T) {
+	t.Parallel()
+
+	var table = []struct {
+		mode fmt.Stringer
+		want string
+	}{
+		{mode: retry.Simple, want: "simple"},
+		{mode: retry.Linear, want: "linear"},
+		{mode: retry.Exponential, want: "exponential"},
+		{mode: retry.Fibonacci, want: "fibonacci"},
+		{mode: retry.FullJitter, want: "full-jitter"},
+		{mode: retry.Decorrelated, want: "decorrelated"},
+		{mode: retry.Constant, want: "constant"},
+	}
+
+	for n, s := range table {
+		if got := s.mode.String(); got != s.want {
+			t.Fatalf("step %d: mode = %q (want: %q)", n, got, s.want)
+		}
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	t.Parallel()
+
+	try := retry.New(
+		retry.Count(3),
+		retry.Sleep(time.Second),
+		retry.Jitter(time.Millisecond),
+		retry.Mode(retry.Exponential),
+	)
+
+	desc := try.Describe()
+
+	for _, want := range []string{
+		"exponential",
+		"base=1s",
+		"count=3",
+		"jitter=1ms",
+		"schedule≈[2.001s,4.001s,8.001s]",
+	} {
+		if !strings.Contains(desc, want) {
+			t.Fatalf("describe: %q - no %q", desc, want)
+		}
+	}
+
+	var table = []struct {
+		try  *retry.Config
+		want []string
+	}{
+		{
+			try:  retry.New(retry.Count(2), retry.EnvelopeBackoff(time.Second, 3*time.Second, 5*time.Second, 2)),
+			want: []string{"full-jitter backoff", "min=3s", "max=5s", "jitter=full", "schedule≈[3s,4s]"},
+		},
+		{
+			try:  retry.New(retry.Count(2), retry.Sleep(time.Second), retry.RandomJitter(5*time.Millisecond)),
+			want: []string{"min=none", "max=none", "jitter=random(5ms)", "schedule≈[1.005s,1.005s]"},
+		},
+		{
+			try: retry.New(
+				retry.Count(1), retry.Sleep(time.Second), retry.Jitter(time.Second/2), retry.JitterDown(true),
+			),
+			want: []string{"jitter=down(500ms)", "schedule≈[1s]"},
+		},
+		{
+			try:  retry.New(retry.Count(1), retry.Sleep(time.Second), retry.SpreadJitter(0.5)),
+			want: []string{"jitter=spread(0.5)", "schedule≈[1.5s]"},
+		},
+		{
+			try:  retry.New(retry.Count(1), retry.Sleep(time.Second), retry.JitterFraction(0.25)),
+			want: []string{"jitter=fraction(0.25)", "schedule≈[1.25s]"},
+		},
+		{
+			try:  retry.New(retry.Count(1), retry.Sleep(time.Second), retry.MaxDelay(time.Second)),
+			want: []string{"max=1s", "jitter=none", "schedule≈[1s]"},
+		},
+		{
+			try:  retry.New(retry.Count(2), retry.Sleep(time.Second), retry.Mode(retry.Decorrelated)),
+			want: []string{"decorrelated backoff", "jitter=decorrelated", "schedule≈[3s,9s]"},
+		},
+	}
+
+	for n, s := range table {
+		desc := s.try.Describe()
+
+		for _, want := range s.want {
+			if !strings.Contains(desc, want) {
+				t.Fatalf("step %d: describe: %q - no %q", n, desc, want)
+			}
+		}
+	}
+}
+
+func TestOnRecovery(t *testing.T) {
+	t.Parallel()
+
+	var (
+		calls    int
+		failures int
+		names    []string
+	)
+
+	try := retry.New(
+		retry.Count(maxRetries),
+		retry.Sleep(time.Millisecond),
+		retry.OnRecovery(func(name string, n int) {
+			names = append(names, name)
+			failures = n
+		}),
+	)
+
+	if err := try.Single("test-first", func() error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(names) > 0 {
+		t.Fatal("callback fired on first-try success")
+	}
+
+	if err := try.Single("test-recovery", func() error {
+		if calls++; calls <= 2 {
+			return errFail
+		}
+
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(names) != 1 || names[0] != "test-recovery" {
+		t.Fatalf("unexpected names: %v", names)
+	}
+
+	if failures != 2 {
+		t.Fatalf("failures = %d (want: 2)", failures)
+	}
+}
+
+func TestMaxDistinctErrors(t *testing.T) {
+	t.Parallel()
+
+	const limit = 2
+
+	var count int
+
+	try := retry.New(
+		retry.Count(9),
+		retry.Sleep(time.Millisecond),
+		retry.MaxDistinctErrors(limit),
+	)
+
+	err := try.Single("test-distinct", func() error {
+		count++
+
+		return fmt.Errorf("unique #%d: %w", count, errFail)
+	})
+	if !errors.Is(err, errFail) {
+		t.Fatalf("err == %v", err)
+	}
+
+	if count != limit+1 {
+		t.Fatalf("count = %d (want: %d)", count, limit+1)
+	}
+
+	count = 0
+
+	err = try.Single("test-same", func() error {
+		count++
+
+		return errFail
+	})
+	if !errors.Is(err, errFail) {
+		t.Fatalf("err == %v", err)
+	}
+
+	if count != 10 {
+		t.Fatalf("count = %d (want: 10)", count)
+	}
+
+	count = 0
+
+	err = try.With(
+		retry.Count(2*retry.MaxErrorHistory),
+		retry.Sleep(time.Nanosecond),
+		retry.MaxDistinctErrors(100),
+	).Single("test-distinct-capped", func() error {
+		count++
+
+		return fmt.Errorf("unique #%d: %w", count, errFail)
+	})
+	if !errors.Is(err, errFail) {
+		t.Fatalf("err == %v", err)
+	}
+
+	if count != retry.MaxErrorHistory {
+		t.Fatalf("count = %d (want: %d)", count, retry.MaxErrorHistory)
+	}
+}
+
+func TestConstant(t *testing.T) {
+	t.Parallel()
+
+	const want = 110 * time.Millisecond
+
+	try := retry.New(
+		retry.Sleep(100*time.Millisecond),
+		retry.Jitter(10*time.Millisecond),
+		retry.Mode(retry.Constant),
+	)
+
+	for n := 1; n <= 10; n++ {
+		if d := try.StepDuration(n); d != want {
+			t.Fatalf("step %d: duration = %s (want: %s)", n, d, want)
+		}
+	}
+}
+
+func TestSchedule(t *testing.T) {
+	t.Parallel()
+
+	const (
+		sleep  = 10 * time.Millisecond
+		jitter = time.Millisecond
+		count  = 5
+	)
+
+	var table = []struct {
+		mode    func(*retry.Config)
+		formula func(n int) time.Duration
+	}{
+		{mode: retry.Mode(retry.Simple), formula: func(n int) time.Duration {
+			return sleep + jitter*time.Duration(n)
+		}},
+		{mode: retry.Mode(retry.Linear), formula: func(n int) time.Duration {
+			return sleep*time.Duration(n) + jitter
+		}},
+		{mode: retry.Mode(retry.Exponential), formula: func(n int) time.Duration {
+			return sleep<<n + jitter
+		}},
+		{mode: retry.Mode(retry.Fibonacci), formula: func(n int) time.Duration {
+			return sleep*time.Duration(retry.FibonacciN(n)) + jitter
+		}},
+		{mode: retry.Mode(retry.Constant), formula: func(int) time.Duration {
+			return sleep + jitter
+		}},
+	}
+
+	for i, s := range table {
+		try := retry.New(
+			retry.Count(count-1),
+			retry.Sleep(sleep),
+			retry.Jitter(jitter),
+			s.mode,
+		)
+
+		sched := try.Schedule()
+		if len(sched) != count-1 {
+			t.Fatalf("step %d: len = %d (want: %d)", i, len(sched), count-1)
+		}
+
+		for n, d := range sched {
+			if want := s.formula(n + 1); d != want {
+				t.Fatalf("step %d: delay %d = %s (want: %s)", i, n, d, want)
+			}
+		}
+	}
+}
+
+func TestFibonacciOverflow(t *testing.T) {
+	t.Parallel()
+
+	const count = 90
+
+	try := retry.New(
+		retry.Count(count-1),
+		retry.Sleep(time.Second),
+		retry.Jitter(time.Millisecond),
+		retry.Mode(retry.Fibonacci),
+	)
+
+	var prev time.Duration
+
+	for n := 1; n <= count; n++ {
+		d := try.StepDuration(n)
+		if d <= 0 {
+			t.Fatalf("step %d: non-positive duration: %d", n, d)
+		}
+
+		if d < prev {
+			t.Fatalf("step %d: non-monotonic duration: %d < %d", n, d, prev)
+		}
+
+		prev = d
+	}
+
+	if prev != math.MaxInt64 {
+		t.Fatalf("not saturated: %d", prev)
+	}
+}
+
+func TestDeadline(t *testing.T) {
+	t.Parallel()
+
+	var count int
+
+	try := retry.New(
+		retry.Count(9),
+		retry.Sleep(30*time.Millisecond),
+		retry.Deadline(time.Now().Add(80*time.Millisecond)),
+	)
+
+	err := try.Single("test-deadline", func() error {
+		count++
+
+		return errFail
+	})
+	if !errors.Is(err, errFail) {
+		t.Fatalf("err == %v", err)
+	}
+
+	// attempts at ~0ms, ~30ms and ~60ms, and one more at ~80ms, after sleep squeezed
+	// to fit deadline, instead of skipping it.
+	if count != maxTries+1 {
+		t.Fatalf("count = %d (want: %d)", count, maxTries+1)
+	}
+}
+
+func TestDeadlinePast(t *testing.T) {
+	t.Parallel()
+
+	var count int
+
+	try := retry.New(
+		retry.Count(maxRetries),
+		retry.Sleep(time.Millisecond),
+		retry.Deadline(time.Now().Add(-time.Second)),
+	)
+
+	err := try.Single("test-deadline-past", func() error {
+		count++
+
+		return errFail
+	})
+	if !errors.Is(err, errFail) {
+		t.Fatalf("err == %v", err)
+	}
+
+	if count != 1 {
+		t.Fatalf("count = %d (want: 1)", count)
+	}
+}
+
+func TestDeadlineCtx(t *testing.T) {
+	t.Parallel()
+
+	var count int
+
+	try := retry.New(
+		retry.Count(maxRetries),
+		retry.Sleep(time.Minute),
+		retry.Deadline(time.Now().Add(time.Hour)),
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+
+	start := time.Now()
+
+	err := try.SingleCtx(ctx, "test-deadline-ctx", func() error {
+		count++
+
+		return errFail
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("err == %v", err)
+	}
+
+	if took := time.Since(start); took > time.Second {
+		t.Fatalf("cancel took %s", took)
+	}
+
+	if count != 1 {
+		t.Fatalf("count = %d (want: 1)", count)
+	}
+}
+
+func TestWrap(t *testing.T) {
+	t.Parallel()
+
+	var count int
+
+	fail := newFailer(errFail, func() { count++ })
+
+	try := retry.New(
+		retry.Count(maxRetries),
+		retry.Sleep(time.Millisecond),
+	)
+
+	wrapped := try.Wrap("test-wrap", fail.Fail)
+
+	for i := 0; i < 2; i++ {
+		fail.Reset(2)
+
+		if err := wrapped(); err != nil {
+			t.Fatalf("step %d: err == %v", i, err)
+		}
+
+		if count != maxTries {
+			t.Fatalf("step %d: count = %d (want: %d)", i, count, maxTries)
+		}
+
+		count = 0
+	}
+}
+
+type syncBuffer struct {
+	buf bytes.Buffer
+	mu  sync.Mutex
+}
+
+func (b *syncBuffer) Write(p []byte) (n int, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.String()
+}
+
+//nolint:paralleltest // modifies global logger
+func TestFatalCause(t *testing.T) {
+	var buf syncBuffer
+
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	var count int
+
+	try := retry.New(
+		retry.Count(maxRetries),
+		retry.Sleep(time.Millisecond),
+		retry.Fatal(errFatal),
+		retry.Verbose(true),
+	)
+
+	err := try.Single("test-cause", func() error {
+		count++
+
+		return fmt.Errorf("outer: %w", fmt.Errorf("inner: %w", errFatal))
+	})
+	if !errors.Is(err, errFatal) {
+		t.Fatalf("err == %v", err)
+	}
+
+	if count != 1 {
+		t.Fatalf("count = %d (want: 1)", count)
+	}
+
+	if want := "test-cause: outer: inner: " + errFatal.Error(); err.Error() != want {
+		t.Fatalf("err = %q (want: %q)", err, want)
+	}
+
+	out := buf.String()
+
+	for _, want := range []string{
+		"outer: inner: " + errFatal.Error(),
+		"matched: " + errFatal.Error(),
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("log: %q - no %q", out, want)
+		}
+	}
+}
+
+func TestSingleWith(t *testing.T) {
+	t.Parallel()
+
+	errLocal := errors.New("local fatal")
+
+	var count int
+
+	try := retry.New(
+		retry.Count(maxRetries),
+		retry.Sleep(time.Millisecond),
+		retry.Fatal(errFatal),
+	)
+
+	fail := func() error {
+		count++
+
+		return errLocal
+	}
+
+	err := try.SingleWith("test-with", fail, retry.Fatal(errLocal))
+	if !errors.Is(err, errLocal) {
+		t.Fatalf("err == %v", err)
+	}
+
+	if count != 1 {
+		t.Fatalf("per-call: count = %d (want: 1)", count)
+	}
+
+	count = 0
+
+	if err = try.Single("test-base", fail); !errors.Is(err, errLocal) {
+		t.Fatalf("err == %v", err)
+	}
+
+	if count != maxTries {
+		t.Fatalf("base: count = %d (want: %d)", count, maxTries)
+	}
+
+	count = 0
+
+	err = try.SingleWith("test-with-base", func() error {
+		count++
+
+		return errFatal
+	}, retry.Fatal(errLocal))
+	if !errors.Is(err, errFatal) {
+		t.Fatalf("err == %v", err)
+	}
+
+	if count != 1 {
+		t.Fatalf("merged: count = %d (want: 1)", count)
+	}
+}
+
+func TestWith(t *testing.T) {
+	t.Parallel()
+
+	errLocal := errors.New("local fatal")
+
+	base := retry.New(
+		retry.Count(maxRetries),
+		retry.Sleep(time.Millisecond),
+		retry.Fatal(errFatal),
+	)
+
+	more := base.With(retry.Count(maxTries*2-1), retry.Fatal(errLocal))
+	other := base.With(retry.Fatal(errFail))
+
+	var table = []struct {
+		try    *retry.Config
+		err    error
+		expect int
+	}{
+		{try: base, err: errFail, expect: maxTries},
+		{try: base, err: errLocal, expect: maxTries},
+		{try: base, err: errFatal, expect: 1},
+		{try: more, err: errFail, expect: maxTries * 2},
+		{try: more, err: errLocal, expect: 1},
+		{try: more, err: errFatal, expect: 1},
+		{try: other, err: errFail, expect: 1},
+		{try: other, err: errLocal, expect: maxTries},
+	}
+
+	for n, s := range table {
+		var count int
+
+		err := s.try.Single("test-with", func() error {
+			count++
+
+			return s.err
+		})
+		if !errors.Is(err, s.err) {
+			t.Fatalf("step %d: err == %v", n, err)
+		}
+
+		if count != s.expect {
+			t.Fatalf("step %d: count = %d (want: %d)", n, count, s.expect)
+		}
+	}
+
+	if a, b := base.Stats().Attempts, more.Stats().Attempts; a != maxTries*2+1 || b != maxTries*2+2 {
+		t.Fatalf("stats are shared: %d / %d", a, b)
+	}
+}
+
+//nolint:paralleltest // modifies global logger
+func TestWarnAboveAttempts(t *testing.T) {
+	var buf syncBuffer
+
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	const succeedOn = 4
+
+	var count int
+
+	try := retry.New(
+		retry.Count(4),
+		retry.Sleep(time.Millisecond),
+		retry.WarnAboveAttempts(2),
+	)
+
+	if err := try.Single("test-warn-quick", func() error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+
+	if out := buf.String(); out != "" {
+		t.Fatalf("unexpected log: %q", out)
+	}
+
+	if err := try.Single("test-warn", func() error {
+		if count++; count < succeedOn {
+			return errFail
+		}
+
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if out := buf.String(); !strings.Contains(out, "test-warn: warning: succeeded after 4 attempts") {
+		t.Fatalf("no warning: %q", out)
+	}
+}
+
+func TestYieldBetween(t *testing.T) {
+	t.Parallel()
+
+	const count = 5
+
+	yield := make(chan struct{}, count)
+
+	try := retry.New(
+		retry.Count(count-1),
+		retry.Sleep(time.Millisecond),
+		retry.YieldBetween(yield),
+	)
+
+	var attempts int
+
+	if err := try.Single("test-yield", func() error {
+		attempts++
+
+		return errFail
+	}); !errors.Is(err, errFail) {
+		t.Fatalf("err == %v", err)
+	}
+
+	if len(yield) != attempts-1 {
+		t.Fatalf("yields = %d (want: %d)", len(yield), attempts-1)
+	}
+
+	// nobody listens: yields are skipped, loop is not blocked.
+	attempts = 0
+
+	if err := try.With(retry.YieldBetween(make(chan struct{}))).Single("test-yield-skip", func() error {
+		attempts++
+
+		return errFail
+	}); !errors.Is(err, errFail) || attempts != count {
+		t.Fatalf("err == %v, attempts = %d (want: %d)", err, attempts, count)
+	}
+}
+
+func TestCountFunc(t *testing.T) {
+	t.Parallel()
+
+	var (
+		load  = []int{4, 1, -1}
+		call  int
+		count int
+	)
+
+	try := retry.New(
+		retry.Count(maxRetries),
+		retry.Sleep(time.Millisecond),
+		retry.CountFunc(func() (rv int) {
+			rv = load[call]
+			call++
+
+			return rv
+		}),
+	)
+
+	for n, want := range []int{5, 2, 1} {
+		if err := try.Single("test-count-func", func() error {
+			count++
+
+			return errFail
+		}); !errors.Is(err, errFail) {
+			t.Fatalf("step %d: err == %v", n, err)
+		}
+
+		if count != want {
+			t.Fatalf("step %d: count = %d (want: %d)", n, count, want)
+		}
+
+		count = 0
+	}
+}
+
+func TestSharedLimit(t *testing.T) {
+	t.Parallel()
+
+	const (
+		limit = 2
+		steps = 4
+	)
+
+	var (
+		inflight atomic.Int32
+		peak     atomic.Int32
+		wg       sync.WaitGroup
+	)
+
+	try := retry.New(
+		retry.Parallelism(limit),
+		retry.SharedLimit(true),
+	)
+
+	work := func() error {
+		cur := inflight.Add(1)
+		defer inflight.Add(-1)
+
+		for {
+			old := peak.Load()
+			if cur <= old || peak.CompareAndSwap(old, cur) {
+				break
+			}
+		}
+
+		time.Sleep(10 * time.Millisecond)
+
+		return nil
+	}
+
+	batch := make([]retry.Step, steps)
+	for i := range batch {
+		batch[i] = retry.Step{Name: fmt.Sprintf("shared-%d", i), Func: work}
+	}
+
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			if err := try.Parallel(batch...); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if p := peak.Load(); p > limit {
+		t.Fatalf("peak = %d (want: <= %d)", p, limit)
+	}
+}
+
+func TestFreeRetry(t *testing.T) {
+	t.Parallel()
+
+	errShortage := errors.New("local shortage")
+
+	const (
+		freeCount = 2
+		maxFree   = 5
+	)
+
+	var table = []struct {
+		freeErrors int
+		countWant  int
+	}{
+		{freeErrors: freeCount, countWant: freeCount + maxTries},
+		{freeErrors: 10, countWant: maxFree + maxTries},
+	}
+
+	try := retry.New(
+		retry.Count(maxRetries),
+		retry.Sleep(time.Millisecond),
+		retry.FreeRetryIf(func(err error) bool {
+			return errors.Is(err, errShortage)
+		}),
+		retry.MaxFreeRetries(maxFree),
+	)
+
+	for n, s := range table {
+		var count int
+
+		err := try.Single("test-free", func() error {
+			if count++; count <= s.freeErrors {
+				return errShortage
+			}
+
+			return errFail
+		})
+		if err == nil {
+			t.Fatalf("step %d: no error", n)
+		}
+
+		if count != s.countWant {
+			t.Fatalf("step %d: count = %d (want: %d)", n, count, s.countWant)
+		}
+	}
+
+	// without limit, number of attempts is used.
+	var count int
+
+	_ = try.With(retry.MaxFreeRetries(0)).Single("test-free-default", func() error {
+		if count++; count <= 10 {
+			return errShortage
+		}
+
+		return errFail
+	})
+
+	if want := 2 * maxTries; count != want {
+		t.Fatalf("default: count = %d (want: %d)", count, want)
+	}
+}
+
+func TestBatchSingle(t *testing.T) {
+	t.Parallel()
+
+	var countA, countB int
+
+	fa := newFailer(errFail, func() { countA++ })
+	fb := newFailer(errFail, func() { countB++ })
+
+	fb.Reset(2)
+
+	try := retry.New(
+		retry.Count(maxRetries),
+		retry.Sleep(time.Millisecond),
+	)
+
+	names := []string{"shard-A", "shard-B"}
+	fns := []func() error{fa.Fail, fb.Fail}
+
+	if err := try.BatchSingle(names, fns); err != nil {
+		t.Fatal(err)
+	}
+
+	if countA != maxTries || countB != maxTries {
+		t.Fatalf("counts = %d/%d (want: %d)", countA, countB, maxTries)
+	}
+
+	fb.Reset(maxTries)
+
+	err := try.BatchSingle(names, fns)
+	if !errors.Is(err, errFail) || !strings.Contains(err.Error(), "shard-B") {
+		t.Fatalf("err == %v", err)
+	}
+
+	if err = try.BatchSingle(names, fns[:1]); !errors.Is(err, retry.ErrBatchMismatch) {
+		t.Fatalf("err == %v", err)
+	}
+}
+
+//nolint:paralleltest // modifies global logger
+func TestLogSchedule(t *testing.T) {
+	var buf syncBuffer
+
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	try := retry.New(
+		retry.Count(maxRetries),
+		retry.Sleep(time.Millisecond),
+		retry.Mode(retry.Exponential),
+		retry.Verbose(true),
+		retry.LogSchedule(true),
+	)
+
+	_ = try.Single("test-schedule", func() error { return errFail })
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+
+	if len(lines) != maxTries+1 {
+		t.Fatalf("lines = %d (want: %d)", len(lines), maxTries+1)
+	}
+
+	if !strings.Contains(lines[0], "test-schedule: schedule: [2ms,4ms] (max 3 tries)") {
+		t.Fatalf("unexpected first line: %q", lines[0])
+	}
+
+	if n := strings.Count(buf.String(), ": schedule: "); n != 1 {
+		t.Fatalf("schedule logged %d times", n)
+	}
+}
+
+//nolint:paralleltest // modifies global logger
+func TestNameFormat(t *testing.T) {
+	var buf syncBuffer
+
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	steps := []retry.Step{
+		{Name: "step-A", Func: func() error { return errFail }},
+	}
+
+	var table = []struct {
+		format  func(string, string) string
+		errWant string
+		logWant string
+	}{
+		{
+			errWant: "chain: step-A: test fail",
+			logWant: "step chain: step-A:0 err",
+		},
+		{
+			format:  func(parent, step string) string { return parent + "." + step },
+			errWant: "chain.step-A: test fail",
+			logWant: "step chain.step-A:0 err",
+		},
+	}
+
+	for n, s := range table {
+		try := retry.New(
+			retry.Sleep(time.Millisecond),
+			retry.Verbose(true),
+			retry.NameFormat(s.format),
+		)
+
+		err := try.Chain(steps...)
+		if !errors.Is(err, errFail) || err.Error() != s.errWant {
+			t.Fatalf("step %d: err = %q (want: %q)", n, err, s.errWant)
+		}
+
+		if out := buf.String(); !strings.Contains(out, s.logWant) {
+			t.Fatalf("step %d: log: %q - no %q", n, out, s.logWant)
+		}
+
+		err = try.Parallel(steps...)
+		if !strings.HasPrefix(err.Error(), strings.Replace(s.errWant, "chain", "parallel", 1)) {
+			t.Fatalf("step %d: err = %q", n, err)
+		}
+	}
+}
+
+func TestOnSharedFailure(t *testing.T) {
+	t.Parallel()
+
+	const steps = 4
+
+	var (
+		down  atomic.Bool
+		hooks atomic.Int32
+	)
+
+	down.Store(true)
+
+	try := retry.New(
+		retry.Count(maxRetries),
+		retry.Sleep(time.Millisecond),
+		retry.OnSharedFailure(func() error {
+			hooks.Add(1)
+			time.Sleep(10 * time.Millisecond)
+			down.Store(false)
+
+			return nil
+		}),
+	)
+
+	work := func() error {
+		if down.Load() {
+			return errFail
+		}
+
+		return nil
+	}
+
+	batch := make([]retry.Step, steps)
+	for i := range batch {
+		batch[i] = retry.Step{Name: fmt.Sprintf("shared-%d", i), Func: work}
+	}
+
+	if err := try.Parallel(batch...); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := hooks.Load(); n != 1 {
+		t.Fatalf("hooks = %d (want: 1)", n)
+	}
+}
+
+func TestCountEdges(t *testing.T) {
+	t.Parallel()
+
+	var table = []struct {
+		retries    int
+		execsWant  int
+		sleepsWant int
+	}{
+		{retries: 0, execsWant: 1, sleepsWant: 0},
+		{retries: 1, execsWant: 2, sleepsWant: 1},
+		{retries: 2, execsWant: 3, sleepsWant: 2},
+		{retries: 3, execsWant: 4, sleepsWant: 3},
+	}
+
+	for n, s := range table {
+		yield := make(chan struct{}, maxTries*2)
+
+		var execs atomic.Int32
+
+		try := retry.New(
+			retry.Count(s.retries),
+			retry.Sleep(time.Millisecond),
+			retry.YieldBetween(yield),
+		)
+
+		fail := func() error {
+			execs.Add(1)
+
+			return errFail
+		}
+
+		runs := []struct {
+			name string
+			fn   func() error
+		}{
+			{name: "single", fn: func() error { return try.Single("edge", fail) }},
+			{name: "chain", fn: func() error { return try.Chain(retry.Step{Name: "edge", Func: fail}) }},
+			{name: "parallel", fn: func() error { return try.Parallel(retry.Step{Name: "edge", Func: fail}) }},
+		}
+
+		for _, r := range runs {
+			execs.Store(0)
+
+			if err := r.fn(); !errors.Is(err, errFail) {
+				t.Fatalf("step %d: %s: err == %v", n, r.name, err)
+			}
+
+			if got := int(execs.Load()); got != s.execsWant {
+				t.Fatalf("step %d: %s: execs = %d (want: %d)", n, r.name, got, s.execsWant)
+			}
+
+			if got := len(yield); got != s.sleepsWant {
+				t.Fatalf("step %d: %s: sleeps = %d (want: %d)", n, r.name, got, s.sleepsWant)
+			}
+
+			for len(yield) > 0 {
+				<-yield
+			}
+		}
+	}
+}
+
+func TestFallback(t *testing.T) {
+	t.Parallel()
+
+	var countA, countB int
+
+	fa := newFailer(errFail, func() { countA++ })
+	fb := newFailer(errFatal, func() { countB++ })
+
+	try := retry.New(
+		retry.Count(maxRetries),
+		retry.Sleep(time.Millisecond),
+	)
+
+	steps := []retry.Step{
+		{Name: "primary", Func: fa.Fail},
+		{Name: "secondary", Func: fb.Fail},
+	}
+
+	var table = []struct {
+		errExpect    []error
+		errCountA    int
+		countAExpect int
+		errCountB    int
+		countBExpect int
+	}{
+		{errCountA: 1, countAExpect: 2, errCountB: 0, countBExpect: 0},
+		{errCountA: maxTries, countAExpect: maxTries, errCountB: 1, countBExpect: 2},
+		{
+			errCountA:    maxTries,
+			countAExpect: maxTries,
+			errCountB:    maxTries,
+			countBExpect: maxTries,
+			errExpect:    []error{errFail, errFatal},
+		},
+	}
+
+	for n, s := range table {
+		fa.Reset(s.errCountA)
+		fb.Reset(s.errCountB)
+
+		err := try.Fallback(steps...)
+		if (err != nil) != (len(s.errExpect) > 0) {
+			t.Fatalf("step %d: err == %v", n, err)
+		}
+
+		for _, e := range s.errExpect {
+			if !errors.Is(err, e) {
+				t.Fatalf("step %d: err == %v (want: %v)", n, err, e)
+			}
+		}
+
+		if countA != s.countAExpect || countB != s.countBExpect {
+			t.Fatalf("step %d: counts = %d/%d (want: %d/%d)", n, countA, countB, s.countAExpect, s.countBExpect)
+		}
+
+		countA, countB = 0, 0
+	}
+}
+
+func TestSequentialBelow(t *testing.T) {
+	t.Parallel()
+
+	var countA, countB int
+
+	fa := newFailer(errFail, func() { countA++ })
+	fb := newFailer(errFatal, func() { countB++ })
+
+	fa.Reset(maxTries)
+	fb.Reset(maxTries)
+
+	try := retry.New(
+		retry.Count(maxRetries),
+		retry.Sleep(time.Millisecond),
+		retry.SequentialBelow(3),
+	)
+
+	err := try.Parallel(
+		retry.Step{Name: "seq-A", Func: fa.Fail},
+		retry.Step{Name: "seq-B", Func: fb.Fail},
+	)
+	if !errors.Is(err, errFail) || errors.Is(err, errFatal) {
+		t.Fatalf("err == %v", err)
+	}
+
+	if countA != maxTries || countB != maxTries {
+		t.Fatalf("counts = %d/%d (want: %d/%d)", countA, countB, maxTries, maxTries)
+	}
+
+	countA, countB = 0, 0
+
+	fa.Reset(maxTries)
+	fb.Reset(maxTries)
+
+	err = try.ParallelCtx(context.Background(),
+		retry.Step{Name: "seq-ctx-A", Func: fa.Fail},
+		retry.Step{Name: "seq-ctx-B", Func: fb.Fail},
+	)
+	if !errors.Is(err, errFail) || errors.Is(err, errFatal) {
+		t.Fatalf("ctx: err == %v", err)
+	}
+
+	if countA != maxTries || countB != 0 {
+		t.Fatalf("ctx: counts = %d/%d (want: %d/0)", countA, countB, maxTries)
+	}
+}
+
+func TestSequentialBelowShared(t *testing.T) {
+	t.Parallel()
+
+	const steps = 2
+
+	var (
+		inflight atomic.Int32
+		peak     atomic.Int32
+		down     atomic.Bool
+		hooks    atomic.Int32
+		wg       sync.WaitGroup
+	)
+
+	down.Store(true)
+
+	try := retry.New(
+		retry.Count(maxRetries),
+		retry.Sleep(time.Millisecond),
+		retry.Parallelism(1),
+		retry.SharedLimit(true),
+		retry.SequentialBelow(steps+1),
+		retry.OnSharedFailure(func() error {
+			hooks.Add(1)
+			down.Store(false)
+
+			return nil
+		}),
+	)
+
+	work := func() error {
+		cur := inflight.Add(1)
+		defer inflight.Add(-1)
+
+		for {
+			old := peak.Load()
+			if cur <= old || peak.CompareAndSwap(old, cur) {
+				break
+			}
+		}
+
+		time.Sleep(10 * time.Millisecond)
+
+		if down.Load() {
+			return errFail
+		}
+
+		return nil
+	}
+
+	batch := make([]retry.Step, steps)
+	for i := range batch {
+		batch[i] = retry.Step{Name: fmt.Sprintf("seq-shared-%d", i), Func: work}
+	}
+
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			if err := try.Parallel(batch...); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if n := peak.Load(); n != 1 {
+		t.Fatalf("peak = %d (want: 1)", n)
+	}
+
+	if n := hooks.Load(); n < 1 || n > 2 {
+		t.Fatalf("hooks = %d (want: once per call)", n)
+	}
+}
+
+func benchmarkParallel(b *testing.B, seqBelow int) {
+	b.Helper()
+
+	try := retry.New(retry.SequentialBelow(seqBelow))
+	ok := func() error { return nil }
+	steps := []retry.Step{
+		{Name: "bench-A", Func: ok},
+		{Name: "bench-B", Func: ok},
+		{Name: "bench-C", Func: ok},
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if err := try.Parallel(steps...); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkParallel(b *testing.B) {
+	benchmarkParallel(b, 0)
+}
+
+func BenchmarkParallelSequential(b *testing.B) {
+	benchmarkParallel(b, 4)
+}
+
+func TestOnStart(t *testing.T) {
+	t.Parallel()
+
+	var (
+		expected time.Duration
+		attempts int
+	)
+
+	try := retry.New(
+		retry.Count(3),
+		retry.Sleep(time.Millisecond),
+		retry.Jitter(time.Millisecond),
+		retry.Mode(retry.Linear),
+		retry.OnStart(func(_ string, d time.Duration, n int) {
+			expected, attempts = d, n
+		}),
+	)
+
+	start := time.Now()
+
+	_ = try.Single("test-start", func() error { return errFail })
+
+	// delays: 1ms+1ms, 2ms+1ms, 3ms+1ms
+	if want := 9 * time.Millisecond; expected != want {
+		t.Fatalf("expected = %s (want: %s)", expected, want)
+	}
+
+	if attempts != 4 {
+		t.Fatalf("attempts = %d (want: 4)", attempts)
+	}
+
+	if took := time.Since(start); took < expected {
+		t.Fatalf("took %s < %s", took, expected)
+	}
+}
+
+func TestSingleCtx(t *testing.T) {
+	t.Parallel()
+
+	var count int
+
+	try := retry.New(
+		retry.Count(maxRetries),
+		retry.Sleep(time.Hour),
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	start := time.Now()
+
+	err := try.SingleCtx(ctx, "test-ctx", func() error {
+		count++
+
+		time.AfterFunc(10*time.Millisecond, cancel)
+
+		return errFail
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("err == %v", err)
+	}
+
+	if took := time.Since(start); took > time.Second {
+		t.Fatalf("cancel took %s", took)
+	}
+
+	if count != 1 {
+		t.Fatalf("count = %d (want: 1)", count)
+	}
+
+	count = 0
+
+	if err = try.SingleCtx(ctx, "test-ctx-done", func() error {
+		count++
+
+		return nil
+	}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("err == %v", err)
+	}
+
+	if count != 0 {
+		t.Fatalf("count = %d (want: 0)", count)
+	}
+}
+
+func TestDelayFirst(t *testing.T) {
+	t.Parallel()
+
+	const sleep = 50 * time.Millisecond
+
+	try := retry.New(
+		retry.Sleep(sleep),
+		retry.DelayFirst(true),
+	)
+
+	start := time.Now()
+
+	var first time.Duration
+
+	if err := try.Single("test-delay-first", func() error {
+		first = time.Since(start)
+
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if first < sleep || first > 10*sleep {
+		t.Fatalf("first call after %s (want: ~%s)", first, sleep)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+
+	var count int
+
+	err := try.With(retry.Sleep(time.Hour)).SingleCtx(ctx, "test-delay-first-ctx", func() error {
+		count++
+
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("err == %v", err)
+	}
+
+	if count != 0 {
+		t.Fatalf("count = %d (want: 0)", count)
+	}
+
+	drain := make(chan struct{})
+	close(drain)
+
+	drained := try.With(retry.Sleep(time.Hour), retry.DrainSignal(drain))
+
+	err = drained.Single("test-delay-first-drain", func() error {
+		count++
+
+		return nil
+	})
+	if !errors.Is(err, retry.ErrDraining) {
+		t.Fatalf("err == %v", err)
+	}
+
+	if count != 0 {
+		t.Fatalf("count = %d (want: 0)", count)
+	}
+
+	// no time left: first attempt starts at once.
+	start = time.Now()
+
+	err = try.With(retry.Sleep(time.Hour), retry.Deadline(time.Now().Add(-time.Second))).Single(
+		"test-delay-first-past", func() error {
+			count++
+
+			return nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if took := time.Since(start); count != 1 || took > time.Second {
+		t.Fatalf("count = %d, took %s (want: 1, at once)", count, took)
+	}
+}
+
+func TestDelayFirstModes(t *testing.T) {
+	t.Parallel()
+
+	const sleep = 30 * time.Millisecond
+
+	table := []time.Duration{sleep, time.Hour}
+
+	var tries = []*retry.Config{
+		retry.New(
+			retry.Sleep(sleep),
+			retry.Mode(retry.Linear),
+			retry.DelayFirst(true),
+		),
+		retry.New(
+			retry.Mode(retry.Fibonacci),
+			retry.BackoffFunc(func(n int) time.Duration {
+				return table[min(n, len(table))-1]
+			}),
+			retry.DelayFirst(true),
+		),
+	}
+
+	for n, try := range tries {
+		var delays []time.Duration
+
+		err := try.With(retry.Inspect(func(s retry.State) {
+			delays = append(delays, s.Delay)
+		})).Single("test-delay-first-mode", func() error { return nil })
+		if err != nil {
+			t.Fatalf("step %d: err == %v", n, err)
+		}
+
+		if len(delays) != 1 || delays[0] != sleep {
+			t.Fatalf("step %d: delays = %v (want: [%s])", n, delays, sleep)
+		}
+	}
+}
+
+func TestInspect(t *testing.T) {
+	t.Parallel()
+
+	var states []retry.State
+
+	fail := newFailer(errFail, func() {})
+	fail.Reset(2)
+
+	try := retry.New(
+		retry.Count(maxRetries),
+		retry.Sleep(time.Millisecond),
+		retry.Mode(retry.Linear),
+		retry.Inspect(func(s retry.State) {
+			states = append(states, s)
+		}),
+	)
+
+	if err := try.Single("test-inspect", fail.Fail); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []retry.State{
+		{Attempt: 1, Delay: 0, Err: errFail},
+		{Attempt: 2, Delay: time.Millisecond, Err: errFail},
+		{Attempt: 3, Delay: 2 * time.Millisecond, Err: nil},
+	}
+
+	if len(states) != len(want) {
+		t.Fatalf("states = %d (want: %d)", len(states), len(want))
+	}
+
+	for i, w := range want {
+		if s := states[i]; s.Attempt != w.Attempt || s.Delay != w.Delay || !errors.Is(s.Err, w.Err) {
+			t.Fatalf("step %d: state = %+v (want: %+v)", i, s, w)
+		}
+	}
+}
+
+func TestChainCtx(t *testing.T) {
+	t.Parallel()
+
+	var countA, countB int
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	try := retry.New(
+		retry.Count(maxRetries),
+		retry.Sleep(time.Millisecond),
+	)
+
+	err := try.ChainCtx(ctx,
+		retry.Step{Name: "ctx-A", Func: func() error {
+			countA++
+
+			cancel()
+
+			return nil
+		}},
+		retry.Step{Name: "ctx-B", Func: func() error {
+			countB++
+
+			return nil
+		}},
+	)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("err == %v", err)
+	}
+
+	if !strings.HasPrefix(err.Error(), "chain: ") {
+		t.Fatalf("err = %q - no prefix", err)
+	}
+
+	if countA != 1 || countB != 0 {
+		t.Fatalf("counts = %d/%d (want: 1/0)", countA, countB)
+	}
+}
+
+func TestParallelCtx(t *testing.T) {
+	t.Parallel()
+
+	const count = 100
+
+	var countB atomic.Int32
+
+	try := retry.New(
+		retry.Count(count-1),
+		retry.Sleep(5*time.Millisecond),
+		retry.Fatal(errFatal),
+	)
+
+	err := try.ParallelCtx(context.Background(),
+		retry.Step{Name: "fatal-A", Func: func() error {
+			time.Sleep(20 * time.Millisecond)
+
+			return errFatal
+		}},
+		retry.Step{Name: "sibling-B", Func: func() error {
+			countB.Add(1)
+
+			return errFail
+		}},
+	)
+	if !errors.Is(err, errFatal) {
+		t.Fatalf("err == %v", err)
+	}
+
+	stopped := countB.Load()
+
+	if stopped >= count {
+		t.Fatalf("sibling was not cancelled: %d attempts", stopped)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+
+	if n := countB.Load(); n != stopped {
+		t.Fatalf("sibling still running: %d -> %d", stopped, n)
+	}
+}
+
+func TestParallelAll(t *testing.T) {
+	t.Parallel()
+
+	try := retry.New(
+		retry.Count(maxRetries),
+		retry.Sleep(time.Millisecond),
+		retry.Fatal(errFatal),
+	)
+
+	err := try.ParallelAll(
+		retry.Step{Name: "all-A", Func: func() error { return errFatal }},
+		retry.Step{Name: "all-B", Func: func() error { return errFail }},
+		retry.Step{Name: "all-C", Func: func() error { return nil }},
+	)
+	if !errors.Is(err, errFatal) || !errors.Is(err, errFail) {
+		t.Fatalf("err == %v", err)
+	}
+
+	for _, want := range []string{"parallel: all-A: ", "parallel: all-B: "} {
+		if !strings.Contains(err.Error(), want) {
+			t.Fatalf("err = %q - no %q", err, want)
+		}
+	}
+
+	if strings.Contains(err.Error(), "all-C") {
+		t.Fatalf("err = %q - has successful step", err)
+	}
+}
+
+func TestStepCount(t *testing.T) {
+	t.Parallel()
+
+	const (
+		retriesA = 1
+		retriesB = 4
+		countA   = retriesA + 1
+		countB   = retriesB + 1
+	)
+
+	try := retry.New(
+		retry.Count(maxRetries),
+		retry.Sleep(time.Millisecond),
+	)
+
+	var callsA, callsB, callsC atomic.Int32
+
+	steps := []retry.Step{
+		{Name: "count-A", Count: retriesA, Func: func() error { callsA.Add(1); return errFail }},
+		{Name: "count-B", Count: retriesB, Func: func() error { callsB.Add(1); return errFail }},
+		{Name: "count-C", Func: func() error { callsC.Add(1); return errFail }},
+	}
+
+	var table = []func(...retry.Step) error{
+		try.ParallelAll,
+		func(s ...retry.Step) error {
+			return errors.Join(try.Chain(s[0]), try.Chain(s[1]), try.Chain(s[2]))
+		},
+	}
+
+	for n, run := range table {
+		callsA.Store(0)
+		callsB.Store(0)
+		callsC.Store(0)
+
+		if err := run(steps...); !errors.Is(err, errFail) {
+			t.Fatalf("step %d: err == %v", n, err)
+		}
+
+		if v := callsA.Load(); v != countA {
+			t.Fatalf("step %d: callsA = %d (want: %d)", n, v, countA)
+		}
+
+		if v := callsB.Load(); v != countB {
+			t.Fatalf("step %d: callsB = %d (want: %d)", n, v, countB)
+		}
+
+		if v := callsC.Load(); v != maxTries {
+			t.Fatalf("step %d: callsC = %d (want: %d)", n, v, maxTries)
+		}
+	}
+}
+
+func TestStepBackoff(t *testing.T) {
+	t.Parallel()
+
+	const count = 4
+
+	var (
+		mu     sync.Mutex
+		totals = make(map[string]time.Duration)
+	)
+
+	try := retry.New(
+		retry.Count(count-1),
+		retry.Sleep(time.Millisecond),
+		retry.Mode(retry.Linear),
+		retry.OnStart(func(name string, d time.Duration, _ int) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			totals[name] = d
+		}),
+	)
+
+	fail := func() error { return errFail }
+
+	err := try.ParallelAll(
+		retry.Step{Name: "linear", Func: fail},
+		retry.Step{Name: "exponential", Func: fail, Mode: retry.Exponential},
+		retry.Step{Name: "slow", Func: fail, Sleep: 2 * time.Millisecond},
+	)
+	if !errors.Is(err, errFail) {
+		t.Fatalf("err == %v", err)
+	}
+
+	var table = []struct {
+		name string
+		want time.Duration
+	}{
+		{name: "parallel: linear", want: (1 + 2 + 3) * time.Millisecond},
+		{name: "parallel: exponential", want: (2 + 4 + 8) * time.Millisecond},
+		{name: "parallel: slow", want: (2 + 4 + 6) * time.Millisecond},
+	}
+
+	for n, s := range table {
+		if got := totals[s.name]; got != s.want {
+			t.Fatalf("step %d: %s total = %s (want: %s)", n, s.name, got, s.want)
+		}
+	}
+}
+
+func TestMaxDelay(t *testing.T) {
+	t.Parallel()
+
+	var table = []struct {
+		maxDelay time.Duration
+		want     []time.Duration
+	}{
+		{
+			want: []time.Duration{
+				210 * time.Millisecond,
+				410 * time.Millisecond,
+				810 * time.Millisecond,
+			},
+		},
+		{
+			maxDelay: 300 * time.Millisecond,
+			want: []time.Duration{
+				210 * time.Millisecond,
+				300 * time.Millisecond,
+				300 * time.Millisecond,
+			},
+		},
+	}
+
+	for n, s := range table {
+		try := retry.New(
+			retry.Sleep(100*time.Millisecond),
+			retry.Jitter(10*time.Millisecond),
+			retry.Mode(retry.Exponential),
+			retry.MaxDelay(s.maxDelay),
+		)
+
+		for i, w := range s.want {
+			if d := try.StepDuration(i + 1); d != w {
+				t.Fatalf("step %d: attempt %d: delay = %s (want: %s)", n, i+1, d, w)
+			}
+		}
+	}
+}
+
+func TestMaxElapsedTime(t *testing.T) {
+	t.Parallel()
+
+	var count int
+
+	try := retry.New(
+		retry.Count(9),
+		retry.Sleep(40*time.Millisecond),
+		retry.MaxElapsedTime(50*time.Millisecond),
+	)
+
+	start := time.Now()
+
+	err := try.Single("test-elapsed", func() error {
+		count++
+
+		return errFail
+	})
+	if !errors.Is(err, errFail) {
+		t.Fatalf("err == %v", err)
+	}
+
+	// attempts at ~0ms, ~40ms and ~50ms, after last sleep squeezed to fit budget.
+	if count != maxTries {
+		t.Fatalf("count = %d (want: %d)", count, maxTries)
+	}
+
+	if took := time.Since(start); took > 80*time.Millisecond {
+		t.Fatalf("took %s", took)
+	}
+}
+
+func TestBackoffFunc(t *testing.T) {
+	t.Parallel()
+
+	table := []time.Duration{
+		5 * time.Millisecond,
+		time.Millisecond,
+		20 * time.Millisecond,
+	}
+
+	try := retry.New(
+		retry.Mode(retry.Exponential),
+		retry.Jitter(time.Millisecond),
+		retry.MaxDelay(15*time.Millisecond),
+		retry.BackoffFunc(func(n int) time.Duration {
+			return table[min(n, len(table))-1]
+		}),
+	)
+
+	want := []time.Duration{
+		6 * time.Millisecond,
+		2 * time.Millisecond,
+		15 * time.Millisecond,
+		15 * time.Millisecond,
+	}
+
+	for i, w := range want {
+		if d := try.StepDuration(i + 1); d != w {
+			t.Fatalf("attempt %d: delay = %s (want: %s)", i+1, d, w)
+		}
+	}
+}
+
+func TestBase(t *testing.T) {
+	t.Parallel()
+
+	var table = []struct {
+		want []time.Duration
+		base float64
+	}{
+		{base: 0, want: []time.Duration{200, 400, 800}},
+		{base: 0.5, want: []time.Duration{200, 400, 800}},
+		{base: 2.0, want: []time.Duration{200, 400, 800}},
+		{base: 1.5, want: []time.Duration{150, 225, 337}},
+		{base: 3.0, want: []time.Duration{300, 900, 2700}},
+	}
+
+	for n, s := range table {
+		try := retry.New(
+			retry.Sleep(100*time.Millisecond),
+			retry.Mode(retry.Exponential),
+			retry.Base(s.base),
+		)
+
+		for i, w := range s.want {
+			// compare with millisecond precision, as float math for non-2 bases may round.
+			if d := try.StepDuration(i + 1).Truncate(time.Millisecond); d != w*time.Millisecond {
+				t.Fatalf("step %d: attempt %d: delay = %s (want: %s)", n, i+1, d, w*time.Millisecond)
+			}
+		}
+	}
+}
+
+func TestExponentialOverflow(t *testing.T) {
+	t.Parallel()
+
+	const count = 100
+
+	var table = []struct {
+		maxDelay time.Duration
+		limit    time.Duration
+		base     float64
+	}{
+		{limit: math.MaxInt64},
+		{maxDelay: time.Minute, limit: time.Minute},
+		{base: 3, limit: math.MaxInt64},
+	}
+
+	for i, s := range table {
+		try := retry.New(
+			retry.Count(count-1),
+			retry.Sleep(time.Second),
+			retry.Jitter(time.Millisecond),
+			retry.Mode(retry.Exponential),
+			retry.MaxDelay(s.maxDelay),
+			retry.Base(s.base),
+		)
+
+		var prev time.Duration
+
+		for n := 1; n <= count; n++ {
+			d := try.StepDuration(n)
+			if d <= 0 || d > s.limit || d < prev {
+				t.Fatalf("step %d: attempt %d: unexpected duration: %d (prev: %d)", i, n, d, prev)
+			}
+
+			prev = d
+		}
+
+		if prev != s.limit {
+			t.Fatalf("step %d: not saturated: %d", i, prev)
+		}
+	}
+}
+
+func TestFibonacciValues(t *testing.T) {
+	t.Parallel()
+
+	var table = []struct {
+		n    int
+		want int64
+	}{
+		{n: 0, want: 0},
+		{n: 1, want: 1},
+		{n: 2, want: 1},
+		{n: 3, want: 2},
+		{n: 10, want: 55},
+		{n: 40, want: 102334155},
+		{n: 92, want: 7540113804746346429},
+		{n: 93, want: math.MaxInt64},
+		{n: 1000, want: math.MaxInt64},
+	}
+
+	for _, s := range table {
+		if got := retry.FibonacciN(s.n); got != s.want {
+			t.Fatalf("fibonacci(%d) = %d (want: %d)", s.n, got, s.want)
+		}
+	}
+}
+
+func fibonacciRecursive(n int) int64 {
+	if n < 2 {
+		return int64(n)
+	}
+
+	return fibonacciRecursive(n-1) + fibonacciRecursive(n-2)
+}
+
+func BenchmarkFibonacci(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		_ = retry.FibonacciN(30)
+	}
+}
+
+func BenchmarkFibonacciRecursive(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		_ = fibonacciRecursive(30)
+	}
+}
+
+func TestSingleN(t *testing.T) {
+	t.Parallel()
+
+	fail := newFailer(errFail, func() {})
+
+	try := retry.New(
+		retry.Count(maxRetries),
+		retry.Sleep(time.Millisecond),
+	)
+
+	var table = []struct {
+		errExpect error
+		errCount  int
+		want      int
+	}{
+		{errCount: 0, want: 1},
+		{errCount: 1, want: 2},
+		{errCount: maxTries, want: maxTries, errExpect: errFail},
+	}
+
+	for n, s := range table {
+		fail.Reset(s.errCount)
+
+		attempts, err := try.SingleN("test-n", fail.Fail)
+		if !errors.Is(err, s.errExpect) {
+			t.Fatalf("step %d: err == %v", n, err)
+		}
+
+		if attempts != s.want {
+			t.Fatalf("step %d: attempts = %d (want: %d)", n, attempts, s.want)
+		}
+	}
+}
+
+func TestRetryIf(t *testing.T) {
+	t.Parallel()
+
+	errBadRequest := errors.New("bad request")
+
+	var count int
+
+	try := retry.New(
+		retry.Count(maxRetries),
+		retry.Sleep(time.Millisecond),
+		retry.Fatal(errFatal),
+		retry.RetryIf(func(err error) bool {
+			return !errors.Is(err, errBadRequest)
+		}),
+	)
+
+	var table = []struct {
+		errs  []error
+		count int
+	}{
+		{errs: []error{errFail, errBadRequest}, count: 2},
+		{errs: []error{errFail, errFail, errFail}, count: maxTries},
+		{errs: []error{errFatal}, count: 1},
+	}
+
+	for n, s := range table {
+		count = 0
+
+		err := try.Single("test-retry-if", func() error {
+			count++
+
+			return s.errs[count-1]
+		})
+		if !errors.Is(err, s.errs[len(s.errs)-1]) {
+			t.Fatalf("step %d: err == %v", n, err)
+		}
+
+		if count != s.count {
+			t.Fatalf("step %d: count = %d (want: %d)", n, count, s.count)
+		}
+	}
+}
+
+func TestSuccessIf(t *testing.T) {
+	t.Parallel()
+
+	errExists := errors.New("already exists")
+
+	try := retry.New(
+		retry.Count(maxRetries),
+		retry.Sleep(time.Millisecond),
+		retry.Fatal(errExists),
+		retry.SuccessIf(func(err error) bool {
+			return errors.Is(err, errExists)
+		}),
+	)
+
+	var table = []struct {
+		err   error
+		want  error
+		count int
+	}{
+		{err: errExists, want: nil, count: 1},
+		{err: fmt.Errorf("create: %w", errExists), want: nil, count: 1},
+		{err: errFail, want: errFail, count: maxTries},
+	}
+
+	for n, s := range table {
+		var count int
+
+		err := try.Single("test-success-if", func() error {
+			count++
+
+			return s.err
+		})
+		if !errors.Is(err, s.want) || (s.want == nil && err != nil) {
+			t.Fatalf("step %d: err == %v", n, err)
+		}
+
+		if count != s.count {
+			t.Fatalf("step %d: count = %d (want: %d)", n, count, s.count)
+		}
+	}
+}
+
+type retryAfterError struct {
+	after time.Duration
+}
+
+func (e *retryAfterError) Error() string {
+	return "retry after " + e.after.String()
+}
+
+func TestDelayFromError(t *testing.T) {
+	t.Parallel()
+
+	const (
+		directed = 7 * time.Millisecond
+		maxDelay = 20 * time.Millisecond
+	)
+
+	var delays []time.Duration
+
+	try := retry.New(
+		retry.Count(3),
+		retry.Sleep(time.Millisecond),
+		retry.MaxDelay(maxDelay),
+		retry.DelayFromError(func(err error) (time.Duration, bool) {
+			var ra *retryAfterError
+			if errors.As(err, &ra) {
+				return ra.after, true
+			}
+
+			return 0, false
+		}),
+		retry.Inspect(func(s retry.State) {
+			delays = append(delays, s.Delay)
+		}),
+	)
+
+	errs := []error{
+		&retryAfterError{after: directed},
+		errFail,
+		&retryAfterError{after: time.Hour},
+		nil,
+	}
+
+	var count int
+
+	if err := try.Single("test-delay-from-error", func() error {
+		count++
+
+		return errs[count-1]
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []time.Duration{0, directed, time.Millisecond, maxDelay}
+
+	if len(delays) != len(want) {
+		t.Fatalf("delays = %v (want: %v)", delays, want)
+	}
+
+	for n, w := range want {
+		if delays[n] != w {
+			t.Fatalf("step %d: delay = %s (want: %s)", n, delays[n], w)
+		}
+	}
+}
+
+func TestSingleReport(t *testing.T) {
+	t.Parallel()
+
+	const sleep = time.Millisecond
+
+	try := retry.New(
+		retry.Count(3),
+		retry.Sleep(sleep),
+		retry.Mode(retry.Linear),
+	)
+
+	var table = []struct {
+		want     retry.Report
+		errCount int
+	}{
+		{
+			errCount: 2,
+			want:     retry.Report{Attempts: 3, TotalSleep: (1 + 2) * sleep, LastDelay: 2 * sleep},
+		},
+		{
+			errCount: 10,
+			want:     retry.Report{Attempts: 4, TotalSleep: (1 + 2 + 3) * sleep, LastDelay: 3 * sleep},
+		},
+	}
+
+	fail := newFailer(errFail, func() {})
+
+	for n, s := range table {
+		fail.Reset(s.errCount)
+
+		rep, err := try.SingleReport("test-report", fail.Fail)
+		if (s.errCount >= s.want.Attempts) != errors.Is(err, errFail) {
+			t.Fatalf("step %d: err == %v", n, err)
+		}
+
+		if rep != s.want {
+			t.Fatalf("step %d: report = %+v (want: %+v)", n, rep, s.want)
+		}
+	}
+}
+
+func TestFatalIf(t *testing.T) {
+	t.Parallel()
+
+	errBadRequest := errors.New("400 bad request")
+
+	try := retry.New(
+		retry.Count(maxRetries),
+		retry.Sleep(time.Millisecond),
+		retry.Fatal(errFatal),
+		retry.FatalIf(func(err error) bool {
+			return strings.Contains(err.Error(), "bad request")
+		}),
+	)
+
+	var table = []struct {
+		err   error
+		count int
+	}{
+		{err: errBadRequest, count: 1},
+		{err: fmt.Errorf("call: %w", errBadRequest), count: 1},
+		{err: errFatal, count: 1},
+		{err: errFail, count: maxTries},
+	}
+
+	for n, s := range table {
+		var count int
+
+		err := try.Single("test-fatal-if", func() error {
+			count++
+
+			return s.err
+		})
+		if !errors.Is(err, s.err) {
+			t.Fatalf("step %d: err == %v", n, err)
+		}
+
+		if count != s.count {
+			t.Fatalf("step %d: count = %d (want: %d)", n, count, s.count)
+		}
+	}
+}
+
+func TestAny(t *testing.T) {
+	t.Parallel()
+
+	try := retry.New(
+		retry.Count(99),
+		retry.Sleep(5*time.Millisecond),
+	)
+
+	var slow atomic.Int32
+
+	winner, err := try.Any(context.Background(),
+		retry.Step{Name: "any-slow", Func: func() error {
+			slow.Add(1)
+
+			return errFail
+		}},
+		retry.Step{Name: "any-fast", Func: func() error {
+			time.Sleep(20 * time.Millisecond)
+
+			return nil
+		}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if winner != "any-fast" {
+		t.Fatalf("winner = %q", winner)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+
+	stopped := slow.Load()
+
+	time.Sleep(20 * time.Millisecond)
+
+	if n := slow.Load(); n != stopped || n >= 100 {
+		t.Fatalf("slow step was not cancelled: %d -> %d", stopped, n)
+	}
+
+	winner, err = try.With(retry.Count(maxRetries), retry.Sleep(time.Millisecond)).Any(context.Background(),
+		retry.Step{Name: "any-A", Func: func() error { return errFail }},
+		retry.Step{Name: "any-B", Func: func() error { return errFatal }},
+	)
+	if !errors.Is(err, errFail) || !errors.Is(err, errFatal) {
+		t.Fatalf("err == %v", err)
+	}
+
+	if winner != "" {
+		t.Fatalf("winner = %q", winner)
+	}
+
+	for _, want := range []string{"any: any-A: ", "any: any-B: "} {
+		if !strings.Contains(err.Error(), want) {
+			t.Fatalf("err = %q - no %q", err, want)
+		}
+	}
+}
+
+func TestAnyWinner(t *testing.T) {
+	t.Parallel()
+
+	try := retry.New(retry.Count(maxRetries))
+
+	delayed := func(d time.Duration) func() error {
+		return func() error {
+			time.Sleep(d)
+
+			return nil
+		}
+	}
+
+	winner, err := try.Any(context.Background(),
+		retry.Step{Name: "winner-slow", Func: delayed(200 * time.Millisecond)},
+		retry.Step{Name: "winner-fast", Func: delayed(time.Millisecond)},
+		retry.Step{Name: "winner-medium", Func: delayed(100 * time.Millisecond)},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if winner != "winner-fast" {
+		t.Fatalf("winner = %q (want: %q)", winner, "winner-fast")
+	}
+}
+
+func TestOnGiveUp(t *testing.T) {
+	t.Parallel()
+
+	type giveUp struct {
+		err      error
+		name     string
+		attempts int
+	}
+
+	var calls []giveUp
+
+	try := retry.New(
+		retry.Count(maxRetries),
+		retry.Sleep(time.Millisecond),
+		retry.Fatal(errFatal),
+		retry.OnGiveUp(func(name string, attempts int, err error) {
+			calls = append(calls, giveUp{name: name, attempts: attempts, err: err})
+		}),
+	)
+
+	fail := newFailer(errFail, func() {})
+
+	fail.Reset(1)
+
+	if err := try.Single("test-recovered", fail.Fail); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := try.Single("test-fatal", func() error { return errFatal }); !errors.Is(err, errFatal) {
+		t.Fatalf("err == %v", err)
+	}
+
+	if len(calls) != 0 {
+		t.Fatalf("callback fired: %+v", calls)
+	}
+
+	fail.Reset(maxTries)
+
+	err := try.Single("test-give-up", fail.Fail)
+	if !errors.Is(err, errFail) {
+		t.Fatalf("err == %v", err)
+	}
+
+	if len(calls) != 1 {
+		t.Fatalf("calls = %d (want: 1)", len(calls))
+	}
+
+	if c := calls[0]; c.name != "test-give-up" || c.attempts != maxTries || !errors.Is(c.err, err) {
+		t.Fatalf("call = %+v", c)
+	}
+}
+
+func TestOnCancel(t *testing.T) {
+	t.Parallel()
+
+	var fired atomic.Int32
+
+	try := retry.New(
+		retry.Count(maxRetries),
+		retry.Sleep(time.Millisecond),
+		retry.OnCancel(func() { fired.Add(1) }),
+	)
+
+	if err := try.Single("cancel-ok", func() error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := try.Single("cancel-fail", func() error { return errFail }); !errors.Is(err, errFail) {
+		t.Fatalf("err == %v", err)
+	}
+
+	if n := fired.Load(); n != 0 {
+		t.Fatalf("fired on success or exhaustion: %d", n)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+
+	start := time.Now()
+
+	err := try.With(retry.Sleep(time.Hour)).SingleCtx(ctx, "cancel-wait", func() error { return errFail })
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("err == %v", err)
+	}
+
+	if took := time.Since(start); took > time.Second {
+		t.Fatalf("backoff was not aborted: %s", took)
+	}
+
+	if n := fired.Load(); n != 1 {
+		t.Fatalf("fired %d times (want: 1)", n)
+	}
+}
+
+func TestOnFatal(t *testing.T) {
+	t.Parallel()
+
+	var (
+		fatals  []int
+		giveUps int
+	)
+
+	try := retry.New(
+		retry.Count(maxRetries),
+		retry.Sleep(time.Millisecond),
+		retry.Fatal(errFatal),
+		retry.OnFatal(func(name string, attempt int, err error) {
+			if !strings.HasPrefix(err.Error(), name+": ") {
+				t.Errorf("unexpected call: %s %v", name, err)
+			}
+
+			fatals = append(fatals, attempt)
+		}),
+		retry.OnGiveUp(func(string, int, error) {
+			giveUps++
+		}),
+	)
+
+	var count int
+
+	err := try.Single("test-on-fatal", func() error {
+		if count++; count < 2 {
+			return errFail
+		}
+
+		return errFatal
+	})
+	if !errors.Is(err, errFatal) {
+		t.Fatalf("err == %v", err)
+	}
+
+	if len(fatals) != 1 || fatals[0] != 2 {
+		t.Fatalf("fatals = %v (want: [2])", fatals)
+	}
+
+	if giveUps != 0 {
+		t.Fatalf("give ups = %d (want: 0)", giveUps)
+	}
+
+	if err = try.Single("test-permanent", func() error {
+		return retry.Permanent(errFail)
+	}); !errors.Is(err, errFail) {
+		t.Fatalf("err == %v", err)
+	}
+
+	if len(fatals) != 2 || fatals[1] != 1 {
+		t.Fatalf("fatals = %v (want: [2 1])", fatals)
+	}
+}
+
+func TestParallelMap(t *testing.T) {
+	t.Parallel()
+
+	ok := func() error { return nil }
+	fail := func() error { return errFail }
+
+	steps := []retry.Step{
+		{Name: "db", Func: ok},
+		{Name: "cache", Func: fail},
+		{Name: "db", Func: fail},
+		{Name: "queue", Func: ok},
+		{Name: "db", Func: ok},
+	}
+
+	want := map[string]error{
+		"db":    nil,
+		"cache": errFail,
+		"db#2":  errFail,
+		"queue": nil,
+		"db#3":  nil,
+	}
+
+	for n, try := range []*retry.Config{
+		retry.New(retry.Count(maxRetries), retry.Sleep(time.Millisecond)),
+		retry.New(retry.Count(maxRetries), retry.Sleep(time.Millisecond), retry.SequentialBelow(10)),
+	} {
+		rv := try.ParallelMap(steps...)
+		if len(rv) != len(want) {
+			t.Fatalf("step %d: results = %v", n, rv)
+		}
+
+		for name, w := range want {
+			err, found := rv[name]
+			if !found {
+				t.Fatalf("step %d: no result for %q", n, name)
+			}
+
+			if (w == nil) != (err == nil) || !errors.Is(err, w) {
+				t.Fatalf("step %d: %s: err == %v (want: %v)", n, name, err, w)
+			}
+		}
+	}
+}
+
+func TestChainByPriority(t *testing.T) {
+	t.Parallel()
+
+	try := retry.New(
+		retry.Count(maxRetries),
+		retry.Sleep(time.Millisecond),
+	)
+
+	var order []string
+
+	step := func(name string, prio int) retry.Step {
+		return retry.Step{Name: name, Priority: prio, Func: func() error {
+			order = append(order, name)
+
+			return nil
+		}}
+	}
+
+	steps := []retry.Step{
+		step("low", -1),
+		step("mid-A", 0),
+		step("high", 10),
+		step("mid-B", 0),
+	}
+
+	if err := try.ChainByPriority(steps...); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"high", "mid-A", "mid-B", "low"}
+
+	if !slices.Equal(order, want) {
+		t.Fatalf("order = %v (want: %v)", order, want)
+	}
+
+	if steps[0].Name != "low" {
+		t.Fatal("input steps reordered")
+	}
+}
+
+func TestFatalIfMerge(t *testing.T) {
+	t.Parallel()
+
+	errBase := errors.New("base fatal")
+	errLocal := errors.New("local fatal")
+
+	is := func(target error) func(error) bool {
+		return func(err error) bool { return errors.Is(err, target) }
+	}
+
+	try := retry.New(
+		retry.Count(maxRetries),
+		retry.Sleep(time.Millisecond),
+		retry.FatalIf(is(errBase)),
+	)
+
+	var table = []struct {
+		run   func(fn func() error) error
+		err   error
+		count int
+	}{
+		{run: func(fn func() error) error {
+			return try.SingleWith("fatal-if-with", fn, retry.FatalIf(is(errLocal)))
+		}, err: errBase, count: 1},
+		{run: func(fn func() error) error {
+			return try.SingleWith("fatal-if-with", fn, retry.FatalIf(is(errLocal)))
+		}, err: errLocal, count: 1},
+		{run: func(fn func() error) error {
+			return try.With(retry.FatalIf(is(errLocal))).Single("fatal-if-derived", fn)
+		}, err: errBase, count: 1},
+		{run: func(fn func() error) error {
+			return try.Single("fatal-if-base", fn)
+		}, err: errLocal, count: maxTries},
+	}
+
+	for n, s := range table {
+		var count int
+
+		err := s.run(func() error {
+			count++
+
+			return s.err
+		})
+		if !errors.Is(err, s.err) {
+			t.Fatalf("step %d: err == %v", n, err)
+		}
+
+		if count != s.count {
+			t.Fatalf("step %d: count = %d (want: %d)", n, count, s.count)
+		}
+	}
+}
+
+func TestNegativeOptions(t *testing.T) {
+	t.Parallel()
+
+	const sleep = 10 * time.Millisecond
+
+	try := retry.New(
+		retry.Count(-1),
+		retry.Sleep(sleep),
+		retry.Jitter(-time.Second),
+		retry.RandomJitter(-time.Second),
+		retry.JitterFraction(-1),
+		retry.SpreadJitter(-1),
+		retry.MaxDelay(-time.Second),
+		retry.MinDelay(-time.Second),
+		retry.Parallelism(-1),
+		retry.MaxDistinctErrors(-1),
+		retry.MaxFreeRetries(-1),
+	)
+
+	for n := 1; n <= maxTries; n++ {
+		if d := try.StepDuration(n); d != sleep {
+			t.Fatalf("attempt %d: delay %s (want: %s)", n, d, sleep)
+		}
+	}
+
+	want := "simple backoff, base=10ms, min=none, max=none, count=0, jitter=none; schedule≈[]"
+	if d := try.Describe(); d != want {
+		t.Fatalf("describe = %q (want: %q)", d, want)
+	}
+
+	var count int
+
+	if err := try.Single("test-negative", func() error {
+		count++
+
+		return errFail
+	}); !errors.Is(err, errFail) {
+		t.Fatalf("err == %v", err)
+	}
+
+	if count != 1 {
+		t.Fatalf("count = %d (want: 1)", count)
+	}
+}
@@ -0,0 +1,60 @@
+package retry
+
+import (
+	"context"
+	"time"
+)
+
+// paramsKey is context key, under which effective `Params` of running call are stored.
+type paramsKey struct{}
+
+// lastKey is context key, that marks context of last attempt of running call.
+type lastKey struct{}
+
+// Params holds effective parameters of running call, see `ConfigFromContext`.
+type Params struct {
+	// Count is number of retries, so call makes at most Count+1 attempts.
+	Count    int
+	Sleep    time.Duration
+	MaxDelay time.Duration
+	Mode     mode
+}
+
+// ConfigFromContext returns effective parameters of call, that runs step, from context,
+// passed to it by context-aware methods (i.e. `DoCtx`), ok is false, if there are none.
+func ConfigFromContext(ctx context.Context) (p Params, ok bool) {
+	p, ok = ctx.Value(paramsKey{}).(Params)
+
+	return p, ok
+}
+
+// IsLastAttempt reports whenever context, passed to step by context-aware methods
+// (i.e. `DoCtx`), belongs to last attempt, permitted by `Count`, so step may try harder.
+func IsLastAttempt(ctx context.Context) (yes bool) {
+	yes, _ = ctx.Value(lastKey{}).(bool)
+
+	return yes
+}
+
+func withParams(ctx context.Context, p Params) context.Context {
+	return context.WithValue(ctx, paramsKey{}, p)
+}
+
+// params returns effective parameters of call, with given number of attempts.
+func (c *Config) params(count int) Params {
+	return Params{
+		Count:    count - 1,
+		Sleep:    c.sleep,
+		MaxDelay: c.maxDelay,
+		Mode:     c.mode,
+	}
+}
+
+// attemptCtx returns context for attempt `n` (0-based) of call, marking the last one.
+func (r *run) attemptCtx(n int) context.Context {
+	if !r.aware || n+1 < r.count {
+		return r.ctx
+	}
+
+	return context.WithValue(r.ctx, lastKey{}, true)
+}
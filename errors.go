@@ -0,0 +1,141 @@
+package retry
+
+import "errors"
+
+var (
+	// ErrCircuitOpen is returned, when call is rejected by `CircuitOpen` predicate.
+	ErrCircuitOpen = errors.New("circuit open")
+	// ErrBatchMismatch is returned by `BatchSingle`, when names and functions counts differ.
+	ErrBatchMismatch = errors.New("batch: names and functions mismatch")
+	// ErrStale is returned, when call exceeds its `Freshness` limit.
+	ErrStale = errors.New("stale")
+	// ErrBudgetExhausted is returned, when `BudgetRemaining` reports no budget left,
+	// or `RetryBudget` has no tokens.
+	ErrBudgetExhausted = errors.New("budget exhausted")
+	// ErrDraining is returned, when call is stopped by `DrainSignal`.
+	ErrDraining = errors.New("draining")
+	// ErrCanceled is returned, when call is stopped by `Session.Cancel`.
+	ErrCanceled = errors.New("canceled")
+	// ErrPanic wraps panics recovered from step functions, see `RecoverPanics`.
+	ErrPanic = errors.New("panic")
+
+	// Unrecoverable stops retrying immediately, when matched by error from step function,
+	// see `Permanent`.
+	Unrecoverable = errors.New("unrecoverable") //nolint:errname // part of public api
+
+	errGiveUp = errors.New("give up")
+)
+
+// Retryable marks `err` as retryable, so it is retried, even if `RetryIf` predicate rejects it,
+// nil stays nil.
+func Retryable(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &RetryableError{Err: err}
+}
+
+// RetryableError is an error, marked by `Retryable`.
+type RetryableError struct {
+	Err error
+}
+
+func (e *RetryableError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *RetryableError) Unwrap() error {
+	return e.Err
+}
+
+// isRetryable reports whenever `err` is marked by `Retryable`.
+func isRetryable(err error) bool {
+	var re *RetryableError
+
+	return errors.As(err, &re)
+}
+
+// Permanent marks `err` as unrecoverable, so step stops without further attempts,
+// returning `err` itself, nil stays nil.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &permanentError{Err: err}
+}
+
+type permanentError struct {
+	Err error
+}
+
+func (e *permanentError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *permanentError) Unwrap() error {
+	return e.Err
+}
+
+func (e *permanentError) Is(target error) bool {
+	return target == Unrecoverable //nolint:errorlint // exact match
+}
+
+// unpermanent strips `Permanent` mark from `err`.
+func unpermanent(err error) error {
+	if p := (*permanentError)(nil); errors.As(err, &p) {
+		return p.Err
+	}
+
+	return err
+}
+
+// ExhaustedError is returned, when step gives up retrying.
+type ExhaustedError struct {
+	Err      error
+	Name     string
+	Category string
+}
+
+func (e *ExhaustedError) Error() string {
+	return e.Name + ": " + e.Err.Error()
+}
+
+func (e *ExhaustedError) Unwrap() error {
+	return e.Err
+}
+
+// TimeoutError is returned, when step gives up retrying, as `Deadline` or `MaxElapsedTime`
+// is reached, it wraps `ExhaustedError`.
+type TimeoutError struct {
+	Err error
+}
+
+func (e *TimeoutError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *TimeoutError) Unwrap() error {
+	return e.Err
+}
+
+// Timeout reports error as timeout, like `net.Error` does.
+func (e *TimeoutError) Timeout() bool {
+	return true
+}
+
+// FatalError is returned, when step is stopped by fatal error.
+type FatalError struct {
+	Err     error
+	Name    string
+	Attempt int
+}
+
+func (e *FatalError) Error() string {
+	return e.Name + ": " + e.Err.Error()
+}
+
+func (e *FatalError) Unwrap() error {
+	return e.Err
+}
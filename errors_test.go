@@ -0,0 +1,524 @@
+package retry_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/s0rg/retry"
+)
+
+func TestClassify(t *testing.T) {
+	t.Parallel()
+
+	errNetwork := errors.New("connection reset")
+
+	try := retry.New(
+		retry.Count(1),
+		retry.Sleep(time.Millisecond),
+		retry.Classify(func(err error) string {
+			switch {
+			case errors.Is(err, errNetwork):
+				return "network"
+			case errors.Is(err, errFatal):
+				return "auth"
+			}
+
+			return "unknown"
+		}),
+	)
+
+	var table = []struct {
+		err      error
+		category string
+	}{
+		{err: errNetwork, category: "network"},
+		{err: errFatal, category: "auth"},
+		{err: errFail, category: "unknown"},
+	}
+
+	for n, s := range table {
+		err := try.Single("test-classify", func() error { return s.err })
+
+		var exh *retry.ExhaustedError
+
+		if !errors.As(err, &exh) {
+			t.Fatalf("step %d: unexpected error type: %T", n, err)
+		}
+
+		if exh.Category != s.category {
+			t.Fatalf("step %d: category = %q (want: %q)", n, exh.Category, s.category)
+		}
+
+		if exh.Name != "test-classify" || !errors.Is(err, s.err) {
+			t.Fatalf("step %d: err == %v", n, err)
+		}
+
+		if want := "test-classify: " + s.err.Error(); err.Error() != want {
+			t.Fatalf("step %d: err = %q (want: %q)", n, err, want)
+		}
+	}
+}
+
+func TestCircuitOpen(t *testing.T) {
+	t.Parallel()
+
+	var (
+		open  bool
+		count int
+	)
+
+	try := retry.New(
+		retry.Count(maxRetries),
+		retry.Sleep(time.Millisecond),
+		retry.CircuitOpen(func() bool { return open }),
+	)
+
+	fn := func() error {
+		count++
+
+		return nil
+	}
+
+	if err := try.Single("test-circuit", fn); err != nil {
+		t.Fatal(err)
+	}
+
+	open = true
+
+	if err := try.Single("test-circuit", fn); !errors.Is(err, retry.ErrCircuitOpen) {
+		t.Fatalf("err == %v", err)
+	}
+
+	if count != 1 {
+		t.Fatalf("count = %d (want: 1)", count)
+	}
+}
+
+func TestRecoverPanics(t *testing.T) {
+	t.Parallel()
+
+	var count int
+
+	fn := func() error {
+		count++
+
+		defer func() {
+			if count < maxTries {
+				panic("cleanup failed")
+			}
+		}()
+
+		return nil
+	}
+
+	try := retry.New(
+		retry.Count(maxRetries),
+		retry.Sleep(time.Millisecond),
+		retry.RecoverPanics(true),
+	)
+
+	if err := try.Single("test-panic", fn); err != nil {
+		t.Fatal(err)
+	}
+
+	if count != maxTries {
+		t.Fatalf("count = %d (want: %d)", count, maxTries)
+	}
+
+	count = 0
+
+	err := try.SingleWith("test-panic-fatal", fn, retry.Fatal(retry.ErrPanic))
+	if !errors.Is(err, retry.ErrPanic) {
+		t.Fatalf("err == %v", err)
+	}
+
+	if count != 1 {
+		t.Fatalf("fatal: count = %d (want: 1)", count)
+	}
+}
+
+func TestFreshness(t *testing.T) {
+	t.Parallel()
+
+	const (
+		sleep     = 40 * time.Millisecond
+		freshness = 100 * time.Millisecond
+	)
+
+	var count int
+
+	try := retry.New(
+		retry.Count(9),
+		retry.Sleep(sleep),
+		retry.Freshness(freshness),
+	)
+
+	start := time.Now()
+
+	err := try.Single("test-stale", func() error {
+		count++
+
+		return errFail
+	})
+	if !errors.Is(err, retry.ErrStale) || !errors.Is(err, errFail) {
+		t.Fatalf("err == %v", err)
+	}
+
+	took := time.Since(start)
+
+	if took < freshness || took > freshness+sleep/2 {
+		t.Fatalf("took %s (want: ~%s)", took, freshness)
+	}
+
+	// attempts at ~0ms, ~40ms and ~80ms, then aborted mid-backoff at ~100ms.
+	if count != maxTries {
+		t.Fatalf("count = %d (want: %d)", count, maxTries)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+
+	slow := try.With(retry.Sleep(time.Hour), retry.Freshness(time.Minute))
+
+	err = slow.SingleCtx(ctx, "test-stale-ctx", func() error { return errFail })
+	if !errors.Is(err, context.Canceled) || errors.Is(err, retry.ErrStale) {
+		t.Fatalf("ctx: err == %v", err)
+	}
+}
+
+func TestFatalAttempt(t *testing.T) {
+	t.Parallel()
+
+	var count int
+
+	try := retry.New(
+		retry.Count(maxRetries),
+		retry.Sleep(time.Millisecond),
+		retry.Fatal(errFatal),
+	)
+
+	err := try.Single("test-fatal-attempt", func() error {
+		if count++; count < 2 {
+			return errFail
+		}
+
+		return errFatal
+	})
+
+	var fe *retry.FatalError
+
+	if !errors.As(err, &fe) {
+		t.Fatalf("unexpected error type: %T", err)
+	}
+
+	if fe.Attempt != 2 || fe.Name != "test-fatal-attempt" || !errors.Is(err, errFatal) {
+		t.Fatalf("unexpected error: %+v", fe)
+	}
+
+	if want := "test-fatal-attempt: " + errFatal.Error(); err.Error() != want {
+		t.Fatalf("err = %q (want: %q)", err, want)
+	}
+}
+
+func TestBudgetRemaining(t *testing.T) {
+	t.Parallel()
+
+	var (
+		budget = 3
+		count  int
+	)
+
+	try := retry.New(
+		retry.Count(9),
+		retry.Sleep(time.Millisecond),
+		retry.BudgetRemaining(func() int { return budget }),
+	)
+
+	fail := func() error {
+		count++
+		budget--
+
+		return errFail
+	}
+
+	err := try.Single("test-budget", fail)
+	if !errors.Is(err, retry.ErrBudgetExhausted) || !errors.Is(err, errFail) {
+		t.Fatalf("err == %v", err)
+	}
+
+	if count != 3 {
+		t.Fatalf("count = %d (want: 3)", count)
+	}
+
+	count = 0
+
+	if err = try.Single("test-budget", fail); !errors.Is(err, retry.ErrBudgetExhausted) {
+		t.Fatalf("err == %v", err)
+	}
+
+	if count != 0 {
+		t.Fatalf("count = %d (want: 0)", count)
+	}
+}
+
+func TestDrainSignal(t *testing.T) {
+	t.Parallel()
+
+	var (
+		drain = make(chan struct{})
+		count int
+	)
+
+	try := retry.New(
+		retry.Count(9),
+		retry.Sleep(time.Hour),
+		retry.DrainSignal(drain),
+	)
+
+	err := try.Single("test-drain", func() error {
+		if count++; count == 1 {
+			close(drain)
+		}
+
+		return errFail
+	})
+	if !errors.Is(err, retry.ErrDraining) || !errors.Is(err, errFail) {
+		t.Fatalf("err == %v", err)
+	}
+
+	if count != 1 {
+		t.Fatalf("count = %d (want: 1)", count)
+	}
+}
+
+func TestRecoverPanicsParallel(t *testing.T) {
+	t.Parallel()
+
+	try := retry.New(
+		retry.Count(1),
+		retry.Sleep(time.Millisecond),
+		retry.RecoverPanics(true),
+	)
+
+	ok := func() error { return nil }
+
+	err := try.Parallel(
+		retry.Step{Name: "calm-A", Func: ok},
+		retry.Step{Name: "culprit", Func: func() error { panic("boom") }},
+		retry.Step{Name: "calm-B", Func: ok},
+	)
+	if !errors.Is(err, retry.ErrPanic) {
+		t.Fatalf("err == %v", err)
+	}
+
+	if want := "parallel: culprit: panic: boom"; err.Error() != want {
+		t.Fatalf("err = %q (want: %q)", err, want)
+	}
+}
+
+func TestCollectErrors(t *testing.T) {
+	t.Parallel()
+
+	errFirst := errors.New("first")
+
+	var count int
+
+	try := retry.New(
+		retry.Count(maxRetries),
+		retry.Sleep(time.Millisecond),
+		retry.CollectErrors(true),
+	)
+
+	step := retry.Step{Name: "collect", Func: func() error {
+		if count++; count == 1 {
+			return errFirst
+		}
+
+		return errFail
+	}}
+
+	for n, run := range []func() error{
+		func() error { return try.Single(step.Name, step.Func) },
+		func() error { return try.Chain(step) },
+		func() error { return try.Parallel(step) },
+	} {
+		count = 0
+
+		err := run()
+		if !errors.Is(err, errFirst) || !errors.Is(err, errFail) {
+			t.Fatalf("step %d: err == %v", n, err)
+		}
+
+		if !strings.Contains(err.Error(), "collect: first") {
+			t.Fatalf("step %d: err = %q - no prefix", n, err)
+		}
+	}
+}
+
+func TestPermanent(t *testing.T) {
+	t.Parallel()
+
+	var count int
+
+	try := retry.New(
+		retry.Count(maxRetries),
+		retry.Sleep(time.Millisecond),
+	)
+
+	err := try.Single("permanent", func() error {
+		count++
+
+		return retry.Permanent(errFail)
+	})
+	if !errors.Is(err, errFail) {
+		t.Fatalf("err == %v", err)
+	}
+
+	if errors.Is(err, retry.Unrecoverable) {
+		t.Fatalf("err == %v - still unrecoverable", err)
+	}
+
+	if count != 1 {
+		t.Fatalf("count = %d (want: 1)", count)
+	}
+
+	if want := "permanent: " + errFail.Error(); err.Error() != want {
+		t.Fatalf("err = %q (want: %q)", err, want)
+	}
+
+	perm := retry.Permanent(errFail)
+	if perm.Error() != errFail.Error() || errors.Unwrap(perm) != errFail { //nolint:errorlint // exact match
+		t.Fatalf("permanent = %v", perm)
+	}
+
+	count = 0
+
+	err = try.Single("unrecoverable", func() error {
+		count++
+
+		return fmt.Errorf("wrapped: %w", retry.Unrecoverable)
+	})
+	if !errors.Is(err, retry.Unrecoverable) || count != 1 {
+		t.Fatalf("err == %v, count = %d (want: 1)", err, count)
+	}
+}
+
+func TestPermanentNil(t *testing.T) {
+	t.Parallel()
+
+	try := retry.New(
+		retry.Count(maxRetries),
+		retry.Sleep(time.Millisecond),
+	)
+
+	if err := try.Single("permanent-nil", func() error { return retry.Permanent(nil) }); err != nil {
+		t.Fatalf("err == %v", err)
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	t.Parallel()
+
+	try := retry.New(
+		retry.Count(maxRetries),
+		retry.Sleep(time.Millisecond),
+		retry.RetryIf(func(error) bool { return false }),
+	)
+
+	var table = []struct {
+		err   error
+		count int
+	}{
+		{err: errFail, count: 1},
+		{err: retry.Retryable(errFail), count: maxTries},
+		{err: fmt.Errorf("deep: %w", retry.Retryable(errFail)), count: maxTries},
+	}
+
+	for n, s := range table {
+		var count int
+
+		err := try.Single("test-retryable", func() error {
+			count++
+
+			return s.err
+		})
+		if !errors.Is(err, errFail) {
+			t.Fatalf("step %d: err == %v", n, err)
+		}
+
+		if count != s.count {
+			t.Fatalf("step %d: count = %d (want: %d)", n, count, s.count)
+		}
+	}
+
+	var re *retry.RetryableError
+
+	if !errors.As(retry.Retryable(errFail), &re) || !errors.Is(re.Err, errFail) {
+		t.Fatal("wrapper is not matched")
+	}
+}
+
+func TestRetryableNil(t *testing.T) {
+	t.Parallel()
+
+	try := retry.New(
+		retry.Count(maxRetries),
+		retry.Sleep(time.Millisecond),
+	)
+
+	if err := try.Single("retryable-nil", func() error { return retry.Retryable(nil) }); err != nil {
+		t.Fatalf("err == %v", err)
+	}
+}
+
+func TestTimeoutError(t *testing.T) {
+	t.Parallel()
+
+	type timeout interface {
+		Timeout() bool
+	}
+
+	fail := func() error { return errFail }
+
+	var table = []struct {
+		try     *retry.Config
+		timeout bool
+	}{
+		{try: retry.New(retry.Count(maxRetries), retry.Sleep(time.Millisecond)), timeout: false},
+		{try: retry.New(
+			retry.Count(9),
+			retry.Sleep(10*time.Millisecond),
+			retry.MaxElapsedTime(25*time.Millisecond),
+		), timeout: true},
+		{try: retry.New(
+			retry.Count(9),
+			retry.Sleep(10*time.Millisecond),
+			retry.Deadline(time.Now().Add(-time.Second)),
+		), timeout: true},
+	}
+
+	for n, s := range table {
+		err := s.try.Single("test-timeout", fail)
+		if !errors.Is(err, errFail) {
+			t.Fatalf("step %d: err == %v", n, err)
+		}
+
+		te, ok := err.(timeout) //nolint:errorlint // checks interface, as stdlib users do
+		if ok != s.timeout || (ok && !te.Timeout()) {
+			t.Fatalf("step %d: timeout = %t (want: %t)", n, ok, s.timeout)
+		}
+
+		if ok && !strings.HasPrefix(err.Error(), "test-timeout: ") {
+			t.Fatalf("step %d: err = %q", n, err)
+		}
+
+		var xerr *retry.ExhaustedError
+		if !errors.As(err, &xerr) {
+			t.Fatalf("step %d: err == %v - not exhausted", n, err)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package retry
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+const (
+	eventStart   = "start"
+	eventAttempt = "attempt"
+	eventBackoff = "backoff"
+	eventSuccess = "success"
+	eventGiveUp  = "giveup"
+)
+
+type event struct {
+	Time    time.Time     `json:"time"`
+	Type    string        `json:"type"`
+	Name    string        `json:"name"`
+	Error   string        `json:"error,omitempty"`
+	Attempt int           `json:"attempt,omitempty"`
+	Delay   time.Duration `json:"delay,omitempty"`
+}
+
+// eventLog writes lifecycle events as newline-delimited json.
+type eventLog struct {
+	enc *json.Encoder
+	mu  sync.Mutex
+}
+
+func newEventLog(w io.Writer) *eventLog {
+	return &eventLog{enc: json.NewEncoder(w)}
+}
+
+func (l *eventLog) emit(typ, name string, attempt int, delay time.Duration, err error) {
+	if l == nil {
+		return
+	}
+
+	e := event{
+		Time:    time.Now(),
+		Type:    typ,
+		Name:    name,
+		Attempt: attempt,
+		Delay:   delay,
+	}
+
+	if err != nil {
+		e.Error = err.Error()
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	_ = l.enc.Encode(e)
+}
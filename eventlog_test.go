@@ -0,0 +1,74 @@
+package retry_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/s0rg/retry"
+)
+
+func TestEventLog(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+
+	try := retry.New(
+		retry.Count(maxRetries),
+		retry.Sleep(time.Millisecond),
+		retry.EventLog(&buf),
+	)
+
+	fail := newFailer(errFail, func() {})
+
+	var table = []struct {
+		want     []string
+		errCount int
+	}{
+		{
+			errCount: 1,
+			want:     []string{"start", "attempt", "backoff", "attempt", "success"},
+		},
+		{
+			errCount: maxTries,
+			want: []string{
+				"start", "attempt", "backoff", "attempt", "backoff", "attempt", "giveup",
+			},
+		},
+	}
+
+	for n, s := range table {
+		buf.Reset()
+		fail.Reset(s.errCount)
+
+		_ = try.Single("test-events", fail.Fail)
+
+		var (
+			dec = json.NewDecoder(&buf)
+			got []string
+		)
+
+		for dec.More() {
+			var e struct {
+				Type string `json:"type"`
+				Name string `json:"name"`
+			}
+
+			if err := dec.Decode(&e); err != nil {
+				t.Fatalf("step %d: decode: %v", n, err)
+			}
+
+			if e.Name != "test-events" {
+				t.Fatalf("step %d: name = %q", n, e.Name)
+			}
+
+			got = append(got, e.Type)
+		}
+
+		if !slices.Equal(got, s.want) {
+			t.Fatalf("step %d: events = %v (want: %v)", n, got, s.want)
+		}
+	}
+}
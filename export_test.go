@@ -0,0 +1,40 @@
+package retry
+
+import (
+	"context"
+	"os"
+	"time"
+)
+
+// StepDuration exposes delay computation for tests.
+func (c *Config) StepDuration(n int) time.Duration {
+	return c.stepDuration(n, 0)
+}
+
+// NextDuration exposes delay computation, that depends on previous delay, for tests.
+func (c *Config) NextDuration(n int, prev time.Duration) time.Duration {
+	return c.stepDuration(n, prev)
+}
+
+// State exposes internal loop state for tests.
+type State = state
+
+// Inspect sets internal inspection hook, called after every attempt, with attempt number,
+// delay waited before it and its error.
+func Inspect(fn func(State)) func(*Config) {
+	return func(c *Config) {
+		c.inspect = fn
+	}
+}
+
+// FibonacciN exposes fibonacci numbers for tests.
+var FibonacciN = fibonacci
+
+// ParallelNotify exposes `ParallelSignal` with custom context factory, so tests can cancel
+// it without real signals.
+func (c *Config) ParallelNotify(
+	notify func(context.Context, ...os.Signal) (context.Context, context.CancelFunc),
+	sig ...os.Signal,
+) func(steps ...Step) error {
+	return c.parallelNotify(notify, sig...)
+}
@@ -0,0 +1,79 @@
+package retry
+
+import "context"
+
+// Do runs `fn` with retries, returning value from successful attempt, or zero value
+// along with error, if all attempts failed.
+func Do[T any](c *Config, name string, fn func() (T, error)) (rv T, err error) {
+	err = c.Single(name, func() (ferr error) {
+		rv, ferr = fn()
+
+		return ferr
+	})
+	if err != nil {
+		var zero T
+
+		return zero, err
+	}
+
+	return rv, nil
+}
+
+// DoCtx acts like `Do`, but aborts as soon as `ctx` is done, returning zero value along with
+// its error, `ctx` is passed to every call of `fn`, carrying effective parameters of call,
+// see `ConfigFromContext`.
+func DoCtx[T any](
+	ctx context.Context,
+	c *Config,
+	name string,
+	fn func(context.Context) (T, error),
+) (rv T, err error) {
+	_, err = c.singleCtx(ctx, name, func(actx context.Context) (ferr error) {
+		rv, ferr = fn(actx)
+
+		return ferr
+	})
+	if err != nil {
+		var zero T
+
+		return zero, err
+	}
+
+	return rv, nil
+}
+
+// Wrap returns retrying version of value-returning `fn`, every call of result runs
+// its own retry loop, returning value from successful attempt.
+func Wrap[T any](c *Config, name string, fn func() (T, error)) func() (T, error) {
+	return func() (T, error) {
+		return Do(c, name, fn)
+	}
+}
+
+// TypedStep represents a single execution step, that produces value.
+type TypedStep[T any] struct {
+	Func func() (T, error)
+	Name string
+}
+
+// ParallelResults executes several `steps` in parallel, like `ParallelAll`, returning their
+// values in order of `steps` (zero values for failed ones) and errors of all failed steps joined.
+func ParallelResults[T any](c *Config, steps []TypedStep[T]) (rv []T, err error) {
+	rv = make([]T, len(steps))
+	plain := make([]Step, len(steps))
+
+	for i := 0; i < len(steps); i++ {
+		fn := steps[i].Func
+
+		plain[i] = Step{Name: steps[i].Name, Func: func() error {
+			v, ferr := fn()
+			if ferr == nil {
+				rv[i] = v
+			}
+
+			return ferr
+		}}
+	}
+
+	return rv, c.ParallelAll(plain...)
+}
@@ -0,0 +1,282 @@
+package retry_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/s0rg/retry"
+)
+
+func TestWrapGeneric(t *testing.T) {
+	t.Parallel()
+
+	var count int
+
+	try := retry.New(
+		retry.Count(maxRetries),
+		retry.Sleep(time.Millisecond),
+	)
+
+	wrapped := retry.Wrap(try, "test-wrap-generic", func() (int, error) {
+		if count++; count < maxTries {
+			return count, errFail
+		}
+
+		return count, nil
+	})
+
+	v, err := wrapped()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v != maxTries {
+		t.Fatalf("value = %d (want: %d)", v, maxTries)
+	}
+
+	count = -10
+
+	v, err = wrapped()
+	if !errors.Is(err, errFail) {
+		t.Fatalf("err == %v", err)
+	}
+
+	if v != 0 {
+		t.Fatalf("value = %d (want: 0)", v)
+	}
+}
+
+func TestDo(t *testing.T) {
+	t.Parallel()
+
+	var count int
+
+	try := retry.New(
+		retry.Count(maxRetries),
+		retry.Sleep(time.Millisecond),
+	)
+
+	fn := func() (string, error) {
+		if count++; count < maxTries {
+			return "stale", errFail
+		}
+
+		return "fresh", nil
+	}
+
+	v, err := retry.Do(try, "test-do", fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v != "fresh" {
+		t.Fatalf("value = %q (want: %q)", v, "fresh")
+	}
+
+	count = -10
+
+	v, err = retry.Do(try, "test-do", fn)
+	if !errors.Is(err, errFail) {
+		t.Fatalf("err == %v", err)
+	}
+
+	if v != "" {
+		t.Fatalf("value = %q (want: empty)", v)
+	}
+}
+
+func TestParallelResults(t *testing.T) {
+	t.Parallel()
+
+	try := retry.New(
+		retry.Count(maxRetries),
+		retry.Sleep(time.Millisecond),
+		retry.Parallelism(2),
+	)
+
+	delayed := func(v int, d time.Duration) func() (int, error) {
+		return func() (int, error) {
+			time.Sleep(d)
+
+			return v, nil
+		}
+	}
+
+	var count atomic.Int32
+
+	steps := []retry.TypedStep[int]{
+		{Name: "slow", Func: delayed(1, 30*time.Millisecond)},
+		{Name: "fast", Func: delayed(2, 0)},
+		{Name: "flaky", Func: func() (int, error) {
+			if count.Add(1) < maxTries {
+				return -1, errFail
+			}
+
+			return 3, nil
+		}},
+		{Name: "broken", Func: func() (int, error) { return -1, errFail }},
+		{Name: "medium", Func: delayed(5, 10*time.Millisecond)},
+	}
+
+	rv, err := retry.ParallelResults(try, steps)
+	if !errors.Is(err, errFail) || !strings.Contains(err.Error(), "broken") {
+		t.Fatalf("err == %v", err)
+	}
+
+	if strings.Contains(err.Error(), "flaky") {
+		t.Fatalf("err = %q - has recovered step", err)
+	}
+
+	want := []int{1, 2, 3, 0, 5}
+
+	if len(rv) != len(want) {
+		t.Fatalf("results = %v (want: %v)", rv, want)
+	}
+
+	for n, w := range want {
+		if rv[n] != w {
+			t.Fatalf("step %d: value = %d (want: %d)", n, rv[n], w)
+		}
+	}
+}
+
+func TestDoCtx(t *testing.T) {
+	t.Parallel()
+
+	var count int
+
+	try := retry.New(
+		retry.Count(maxRetries),
+		retry.Sleep(time.Hour),
+	)
+
+	type ctxKey struct{}
+
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), ctxKey{}, "parent"))
+
+	start := time.Now()
+
+	v, err := retry.DoCtx(ctx, try, "test-do-ctx", func(fctx context.Context) (int, error) {
+		if fctx.Value(ctxKey{}) != "parent" {
+			t.Error("context is not passed")
+		}
+
+		count++
+
+		time.AfterFunc(10*time.Millisecond, cancel)
+
+		return count, errFail
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("err == %v", err)
+	}
+
+	if took := time.Since(start); took > time.Second {
+		t.Fatalf("cancel took %s", took)
+	}
+
+	if v != 0 {
+		t.Fatalf("value = %d (want: 0)", v)
+	}
+
+	if count != 1 {
+		t.Fatalf("count = %d (want: 1)", count)
+	}
+}
+
+func TestConfigFromContext(t *testing.T) {
+	t.Parallel()
+
+	if _, ok := retry.ConfigFromContext(context.Background()); ok {
+		t.Fatal("params in empty context")
+	}
+
+	try := retry.New(
+		retry.Count(maxRetries),
+		retry.Sleep(time.Millisecond),
+		retry.MaxDelay(time.Second),
+		retry.Mode(retry.Linear),
+	)
+
+	var table = []struct {
+		try  *retry.Config
+		want retry.Params
+	}{
+		{
+			try:  try,
+			want: retry.Params{Count: maxRetries, Sleep: time.Millisecond, MaxDelay: time.Second, Mode: retry.Linear},
+		},
+		{
+			try:  try.With(retry.NoRetry(), retry.Mode(retry.Constant)),
+			want: retry.Params{Count: 0, Sleep: time.Millisecond, MaxDelay: time.Second, Mode: retry.Constant},
+		},
+	}
+
+	for n, s := range table {
+		var count int
+
+		_, err := retry.DoCtx(context.Background(), s.try, "test-params", func(ctx context.Context) (int, error) {
+			count++
+
+			p, ok := retry.ConfigFromContext(ctx)
+			if !ok {
+				t.Errorf("step %d: no params", n)
+			}
+
+			if p != s.want {
+				t.Errorf("step %d: params = %+v (want: %+v)", n, p, s.want)
+			}
+
+			return 0, errFail
+		})
+		if !errors.Is(err, errFail) {
+			t.Fatalf("step %d: err == %v", n, err)
+		}
+
+		if count != s.want.Count+1 {
+			t.Fatalf("step %d: count = %d (want: %d)", n, count, s.want.Count+1)
+		}
+	}
+}
+
+func TestIsLastAttempt(t *testing.T) {
+	t.Parallel()
+
+	if retry.IsLastAttempt(context.Background()) {
+		t.Fatal("last attempt in empty context")
+	}
+
+	try := retry.New(
+		retry.Count(maxRetries),
+		retry.Sleep(time.Millisecond),
+	)
+
+	var last []bool
+
+	v, err := retry.DoCtx(context.Background(), try, "test-last", func(ctx context.Context) (int, error) {
+		last = append(last, retry.IsLastAttempt(ctx))
+
+		if retry.IsLastAttempt(ctx) {
+			return len(last), nil
+		}
+
+		return 0, errFail
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v != maxTries {
+		t.Fatalf("value = %d (want: %d)", v, maxTries)
+	}
+
+	for n, yes := range last {
+		if want := n == maxTries-1; yes != want {
+			t.Fatalf("step %d: last = %t (want: %t)", n, yes, want)
+		}
+	}
+}
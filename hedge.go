@@ -0,0 +1,80 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Hedge runs retry loop for `fn` and, if it is not succeeded within `after`, launches another
+// one concurrently, and so on, up to `maxConcurrent` loops, returning nil on first success.
+// Failed loop is replaced immediately. Slower loops are cancelled at their next backoff, in
+// background, so `fn` must be idempotent. If all of them fails - returns all errors joined.
+// Non-positive `after` launches all loops at once.
+func (c *Config) Hedge(
+	ctx context.Context,
+	name string,
+	fn func() error,
+	after time.Duration,
+	maxConcurrent int,
+) (err error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	maxConcurrent = max(maxConcurrent, 1)
+	name = c.compose("hedge", name)
+	results := make(chan error, maxConcurrent)
+
+	var (
+		errs           []error
+		launched, done int
+	)
+
+	launch := func() {
+		if launched < maxConcurrent {
+			launched++
+
+			go func() {
+				results <- c.SingleCtx(ctx, name, fn)
+			}()
+		}
+	}
+
+	launch()
+
+	if after <= 0 {
+		for launched < maxConcurrent {
+			launch()
+		}
+	}
+
+	t := time.NewTimer(max(after, 0))
+	defer t.Stop()
+
+	// timer is not needed, once all loops are launched.
+	tick := t.C
+	if launched == maxConcurrent {
+		tick = nil
+	}
+
+	for done < launched {
+		select {
+		case err = <-results:
+			if done++; err == nil {
+				return nil
+			}
+
+			errs = append(errs, err)
+
+			launch()
+		case <-tick:
+			if launch(); launched < maxConcurrent {
+				t.Reset(after)
+			} else {
+				tick = nil
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,112 @@
+package retry_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/s0rg/retry"
+)
+
+func TestHedge(t *testing.T) {
+	t.Parallel()
+
+	try := retry.New(
+		retry.Count(maxRetries),
+		retry.Sleep(time.Millisecond),
+	)
+
+	var (
+		calls  atomic.Int32
+		winner atomic.Int32
+	)
+
+	start := time.Now()
+
+	err := try.Hedge(context.Background(), "test-hedge", func() error {
+		n := calls.Add(1)
+		if n == 1 {
+			time.Sleep(200 * time.Millisecond)
+		}
+
+		winner.CompareAndSwap(0, n)
+
+		return nil
+	}, 10*time.Millisecond, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if took := time.Since(start); took > 100*time.Millisecond {
+		t.Fatalf("hedge took %s", took)
+	}
+
+	if w := winner.Load(); w != 2 {
+		t.Fatalf("winner = %d (want: 2)", w)
+	}
+
+	calls.Store(0)
+
+	err = try.Hedge(context.Background(), "test-hedge-fail", func() error {
+		calls.Add(1)
+
+		return errFail
+	}, time.Hour, 2)
+	if !errors.Is(err, errFail) {
+		t.Fatalf("err == %v", err)
+	}
+
+	if n := calls.Load(); n != 2*maxTries {
+		t.Fatalf("calls = %d (want: %d)", n, 2*maxTries)
+	}
+
+	calls.Store(0)
+
+	// every loop is slow, but the last one: timer is re-armed until all are launched.
+	err = try.Hedge(context.Background(), "test-hedge-chain", func() error {
+		if n := calls.Add(1); n < 3 {
+			time.Sleep(200 * time.Millisecond)
+		}
+
+		return nil
+	}, 10*time.Millisecond, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n := calls.Load(); n != 3 {
+		t.Fatalf("calls = %d (want: 3)", n)
+	}
+}
+
+func TestHedgeNoDelay(t *testing.T) {
+	t.Parallel()
+
+	const loops = 3
+
+	try := retry.New(retry.Count(maxRetries))
+
+	var (
+		calls  atomic.Int32
+		active atomic.Int32
+	)
+
+	err := try.Hedge(context.Background(), "test-hedge-now", func() error {
+		calls.Add(1)
+
+		if n := active.Add(1); n < loops {
+			time.Sleep(50 * time.Millisecond)
+		}
+
+		return nil
+	}, 0, loops)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n := calls.Load(); n != loops {
+		t.Fatalf("calls = %d (want: %d)", n, loops)
+	}
+}
@@ -0,0 +1,43 @@
+package retry
+
+import (
+	"context"
+	"log"
+	"log/slog"
+	"time"
+)
+
+// Logger receives verbose diagnostics, i.e. to bridge them to existing logging pipeline.
+type Logger interface {
+	Printf(format string, args ...any)
+}
+
+// logf writes message to configured logger, or to standard one, if none set.
+func (c *Config) logf(format string, args ...any) {
+	if c.logger != nil {
+		c.logger.Printf(format, args...)
+
+		return
+	}
+
+	log.Printf(format, args...)
+}
+
+// slogRetry writes structured record about failed attempt, followed by `delay`.
+func (c *Config) slogRetry(ctx context.Context, name string, attempt int, delay time.Duration, err error) {
+	c.slog.LogAttrs(ctx, slog.LevelDebug, "retry",
+		slog.String("step", name),
+		slog.Int("attempt", attempt),
+		slog.Any("error", err),
+		slog.Duration("delay", delay),
+	)
+}
+
+// slogGiveUp writes structured record about failed step.
+func (c *Config) slogGiveUp(ctx context.Context, name string, attempts int, err error) {
+	c.slog.LogAttrs(ctx, slog.LevelWarn, "give up",
+		slog.String("step", name),
+		slog.Int("attempt", attempts),
+		slog.Any("error", err),
+	)
+}
@@ -0,0 +1,238 @@
+package retry_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/s0rg/retry"
+)
+
+type fakeLogger struct {
+	lines []string
+}
+
+func (l *fakeLogger) Printf(format string, args ...any) {
+	l.lines = append(l.lines, fmt.Sprintf(format, args...))
+}
+
+func TestWithLogger(t *testing.T) {
+	t.Parallel()
+
+	l := &fakeLogger{}
+
+	try := retry.New(
+		retry.Count(maxRetries),
+		retry.Sleep(time.Millisecond),
+		retry.Fatal(errFatal),
+		retry.Verbose(true),
+		retry.WithLogger(l),
+	)
+
+	var count int
+
+	err := try.Single("logger", func() error {
+		if count++; count < maxTries {
+			return errFail
+		}
+
+		return errFatal
+	})
+	if !errors.Is(err, errFatal) {
+		t.Fatalf("err == %v", err)
+	}
+
+	want := []string{
+		"step logger:0 err: " + errFail.Error(),
+		"step logger:1 err: " + errFail.Error(),
+		"step logger:2 fatal: " + errFatal.Error() + " (matched: " + errFatal.Error() + ")",
+	}
+
+	if len(l.lines) != len(want) {
+		t.Fatalf("lines = %q (want: %q)", l.lines, want)
+	}
+
+	for n, line := range l.lines {
+		if !strings.Contains(line, want[n]) {
+			t.Fatalf("step %d: line = %q (want: %q)", n, line, want[n])
+		}
+	}
+}
+
+type slogRecord struct {
+	attrs map[string]any
+	msg   string
+	level slog.Level
+}
+
+type fakeHandler struct {
+	records *[]slogRecord
+}
+
+func (fakeHandler) Enabled(context.Context, slog.Level) bool { return true }
+func (h fakeHandler) WithAttrs([]slog.Attr) slog.Handler     { return h }
+func (h fakeHandler) WithGroup(string) slog.Handler          { return h }
+
+func (h fakeHandler) Handle(_ context.Context, r slog.Record) error {
+	rec := slogRecord{msg: r.Message, level: r.Level, attrs: make(map[string]any)}
+
+	r.Attrs(func(a slog.Attr) bool {
+		rec.attrs[a.Key] = a.Value.Any()
+
+		return true
+	})
+
+	*h.records = append(*h.records, rec)
+
+	return nil
+}
+
+func TestWithSlog(t *testing.T) {
+	t.Parallel()
+
+	var records []slogRecord
+
+	l := &fakeLogger{}
+
+	try := retry.New(
+		retry.Count(maxRetries),
+		retry.Sleep(time.Millisecond),
+		retry.Verbose(true),
+		retry.WithLogger(l),
+		retry.WithSlog(slog.New(fakeHandler{records: &records})),
+	)
+
+	err := try.Single("slog", func() error {
+		return errFail
+	})
+	if !errors.Is(err, errFail) {
+		t.Fatalf("err == %v", err)
+	}
+
+	if len(l.lines) != 0 {
+		t.Fatalf("verbose lines = %q", l.lines)
+	}
+
+	if len(records) != maxTries {
+		t.Fatalf("records = %d (want: %d)", len(records), maxTries)
+	}
+
+	for n, rec := range records[:maxTries-1] {
+		if rec.level != slog.LevelDebug {
+			t.Fatalf("step %d: level = %v", n, rec.level)
+		}
+
+		if rec.attrs["step"] != "slog" || rec.attrs["attempt"] != int64(n+1) {
+			t.Fatalf("step %d: attrs = %v", n, rec.attrs)
+		}
+
+		if e, ok := rec.attrs["error"].(error); !ok || !errors.Is(e, errFail) {
+			t.Fatalf("step %d: error = %v", n, rec.attrs["error"])
+		}
+
+		if d, ok := rec.attrs["delay"].(time.Duration); !ok || d <= 0 {
+			t.Fatalf("step %d: delay = %v", n, rec.attrs["delay"])
+		}
+	}
+
+	last := records[maxTries-1]
+
+	if last.level != slog.LevelWarn || last.attrs["attempt"] != int64(maxTries) {
+		t.Fatalf("last = %+v", last)
+	}
+
+	if e, ok := last.attrs["error"].(error); !ok || !errors.Is(e, err) {
+		t.Fatalf("last error = %v", last.attrs["error"])
+	}
+}
+
+func TestInvalidBudget(t *testing.T) {
+	t.Parallel()
+
+	for n, b := range []struct {
+		tokens int
+		per    time.Duration
+	}{
+		{0, time.Second},
+		{-1, time.Second},
+		{1, 0},
+		{1, -time.Second},
+	} {
+		l := &fakeLogger{}
+
+		var count int
+
+		try := retry.New(
+			retry.Count(maxRetries),
+			retry.Sleep(time.Millisecond),
+			retry.WithLogger(l),
+			retry.RetryBudget(b.tokens, b.per),
+		)
+
+		_ = try.Single("invalid-budget", func() error {
+			count++
+
+			return errFail
+		})
+
+		if len(l.lines) != 1 || !strings.Contains(l.lines[0], "invalid budget") {
+			t.Fatalf("step %d: lines = %q", n, l.lines)
+		}
+
+		if count != maxTries {
+			t.Fatalf("step %d: count = %d (want: %d)", n, count, maxTries)
+		}
+	}
+}
+
+func TestUnknownMode(t *testing.T) {
+	t.Parallel()
+
+	l := &fakeLogger{}
+
+	try := retry.New(
+		retry.Sleep(10*time.Millisecond),
+		retry.Jitter(time.Millisecond),
+		retry.WithLogger(l),
+		retry.Mode(99),
+	)
+
+	if len(l.lines) != 1 || !strings.Contains(l.lines[0], "unknown mode 99") {
+		t.Fatalf("lines = %q", l.lines)
+	}
+
+	if d := try.Describe(); !strings.HasPrefix(d, "simple backoff") {
+		t.Fatalf("describe = %q", d)
+	}
+
+	if d, want := try.StepDuration(2), 12*time.Millisecond; d != want {
+		t.Fatalf("duration = %s (want: %s)", d, want)
+	}
+}
+
+func TestSharedFailureLog(t *testing.T) {
+	t.Parallel()
+
+	l := &fakeLogger{}
+
+	try := retry.New(
+		retry.Sleep(time.Millisecond),
+		retry.Verbose(true),
+		retry.WithLogger(l),
+		retry.OnSharedFailure(func() error { return errFatal }),
+	)
+
+	_ = try.Parallel(retry.Step{Name: "shared-log", Func: func() error { return errFail }})
+
+	for _, line := range l.lines {
+		if strings.Contains(line, "shared failure hook err: "+errFatal.Error()) {
+			return
+		}
+	}
+
+	t.Fatalf("lines = %q", l.lines)
+}
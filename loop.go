@@ -0,0 +1,423 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// run holds state of single retry loop, it never outlives call and never shared.
+type run struct {
+	ctx      context.Context
+	start    time.Time
+	seen     map[string]struct{}
+	errs     []error
+	name     string
+	prev     time.Duration
+	longest  time.Duration
+	slept    time.Duration
+	count    int
+	attempts int
+	free     int
+	timedOut bool
+	canceled bool
+	aware    bool
+}
+
+// single runs retry loop, returning its report along with error.
+func (c *Config) single(ctx context.Context, name string, fn func() error) (rep Report, err error) {
+	return c.exec(ctx, name, false, func(context.Context) error { return fn() })
+}
+
+// singleCtx acts like `single`, but passes context, that carries effective parameters
+// of call (see `ConfigFromContext`), to every attempt of `fn`.
+func (c *Config) singleCtx(
+	ctx context.Context,
+	name string,
+	fn func(context.Context) error,
+) (rep Report, err error) {
+	return c.exec(ctx, name, true, fn)
+}
+
+func (c *Config) exec(
+	ctx context.Context,
+	name string,
+	aware bool,
+	fn func(context.Context) error,
+) (rep Report, err error) {
+	r := &run{
+		ctx:   ctx,
+		name:  name,
+		start: time.Now(),
+		count: c.count,
+		aware: aware,
+	}
+
+	defer func() {
+		var exhausted *ExhaustedError
+
+		c.stats.observe(r, errors.As(err, &exhausted))
+	}()
+
+	if c.audit != nil {
+		defer func() {
+			c.audit(newAuditRecord(name, r.start, r.attempts, r.longest, r.canceled, err))
+		}()
+	}
+
+	if c.metrics != nil {
+		defer func() {
+			c.metrics.ObserveAttempts(name, r.attempts)
+		}()
+	}
+
+	if c.events != nil {
+		c.events.emit(eventStart, name, 0, 0, nil)
+
+		defer func() {
+			if err != nil {
+				c.events.emit(eventGiveUp, name, r.attempts, 0, err)
+			} else {
+				c.events.emit(eventSuccess, name, r.attempts, 0, nil)
+			}
+		}()
+	}
+
+	if c.slog != nil {
+		defer func() {
+			if err != nil {
+				c.slogGiveUp(ctx, name, r.attempts, err)
+			}
+		}()
+	}
+
+	if c.circuit != nil && c.circuit() {
+		return rep, fmt.Errorf("%s: %w", name, ErrCircuitOpen)
+	}
+
+	c.prepare(r)
+
+	if r.aware {
+		r.ctx = withParams(r.ctx, c.params(r.count))
+	}
+
+	err = c.loop(r, fn)
+
+	if r.canceled && c.onCancel != nil {
+		c.onCancel()
+	}
+
+	return r.report(), err
+}
+
+// prepare fills per-call limits and reports schedule, if requested.
+func (c *Config) prepare(r *run) {
+	if c.distinct > 0 {
+		r.seen = make(map[string]struct{})
+	}
+
+	if c.countFn != nil {
+		r.count = attempts(c.countFn())
+	}
+
+	if c.onStart == nil && (!c.verbose || !c.logSchedule) {
+		return
+	}
+
+	sched := c.maxSchedule(r.count)
+
+	if c.onStart != nil {
+		c.onStart(r.name, total(sched), r.count)
+	}
+
+	if c.verbose && c.logSchedule {
+		c.logf("step %s: schedule: %s (max %d tries)", r.name, formatSchedule(sched), r.count)
+	}
+}
+
+func (c *Config) loop(r *run, fn func(context.Context) error) (err error) {
+	if c.delayFirst {
+		if err = c.warmUp(r); err != nil {
+			if r.ctx.Err() != nil {
+				return r.cancel()
+			}
+
+			return fmt.Errorf("%s: %w", r.name, err)
+		}
+	}
+
+	for n := 0; n < r.count; n++ {
+		if r.ctx.Err() != nil {
+			return r.cancel()
+		}
+
+		if c.remaining != nil && c.remaining() <= 0 {
+			err = budgetExhausted(err)
+
+			break
+		}
+
+		r.attempts++
+
+		err = c.call(r.attemptCtx(n), fn)
+
+		c.events.emit(eventAttempt, r.name, r.attempts, 0, err)
+
+		if c.inspect != nil {
+			c.inspect(state{Attempt: r.attempts, Delay: r.prev, Err: err})
+		}
+
+		if err != nil && c.successIf != nil && c.successIf(err) {
+			err = nil
+		}
+
+		if err == nil {
+			c.succeeded(r)
+
+			return nil
+		}
+
+		if c.collect {
+			r.errs = append(r.errs, err)
+		}
+
+		if errors.Is(err, Unrecoverable) {
+			return c.stop(r, unpermanent(err))
+		}
+
+		if match := c.isFatal(err); match != nil {
+			if c.verbose && c.slog == nil {
+				c.logf("step %s:%d fatal: %v (matched: %v)", r.name, n, err, match)
+			}
+
+			return c.stop(r, err)
+		}
+
+		if c.retryIf != nil && !isRetryable(err) && !c.retryIf(err) {
+			return c.stop(r, err)
+		}
+
+		if r.seen != nil && tooDistinct(r.seen, err, c.distinct) {
+			break
+		}
+
+		if c.verbose && c.slog == nil {
+			c.logf("step %s:%d err: %v", r.name, n, err)
+		}
+
+		if c.freeIf != nil && r.free < c.maxFree && c.freeIf(err) {
+			r.free++
+			n-- // free retry, does not consume attempt.
+		}
+
+		if n+1 >= r.count {
+			break
+		}
+
+		perr := c.pause(r, max(n+1, 1), err)
+
+		if r.ctx.Err() != nil {
+			return r.cancel()
+		}
+
+		if perr != nil {
+			var terr *TimeoutError
+
+			switch {
+			case errors.As(perr, &terr):
+				r.timedOut = true
+			case !errors.Is(perr, errGiveUp):
+				err = fmt.Errorf("%w: %w", perr, err)
+			}
+
+			break
+		}
+	}
+
+	var xerr error = c.exhausted(r.name, r.final(err))
+
+	if r.timedOut {
+		xerr = &TimeoutError{Err: xerr}
+	}
+
+	if c.onGiveUp != nil {
+		c.onGiveUp(r.name, r.attempts, xerr)
+	}
+
+	return xerr
+}
+
+func (c *Config) succeeded(r *run) {
+	if r.attempts > 1 && c.onRecovery != nil {
+		c.onRecovery(r.name, r.attempts-1)
+	}
+
+	if c.warnAbove > 0 && r.attempts > c.warnAbove {
+		c.logf("step %s: warning: succeeded after %d attempts (threshold: %d)",
+			r.name, r.attempts, c.warnAbove)
+	}
+}
+
+// pause awaits before attempt `n`, after `last` error, returns non-nil error, if no more attempts allowed.
+func (c *Config) pause(r *run, n int, last error) (err error) {
+	if c.budget != nil && !c.budget.take() {
+		return ErrBudgetExhausted
+	}
+
+	d := c.stepDuration(n, r.prev)
+
+	if c.delayFrom != nil {
+		if v, ok := c.delayFrom(last); ok {
+			d = max(v, minDuration)
+
+			if c.maxDelay > 0 {
+				d = min(d, c.maxDelay)
+			}
+		}
+	}
+
+	if d, err = c.squeeze(r, d); err != nil {
+		return err
+	}
+
+	if c.freshness > 0 {
+		if left := max(c.freshness-time.Since(r.start), 0); left < d {
+			if err = c.sleepFor(r, left); err != nil {
+				return err
+			}
+
+			return ErrStale
+		}
+	}
+
+	c.events.emit(eventBackoff, r.name, n, d, nil)
+
+	if c.slog != nil {
+		c.slogRetry(r.ctx, r.name, r.attempts, d, last)
+	}
+
+	if c.yield != nil {
+		select {
+		case c.yield <- struct{}{}:
+		default:
+		}
+	}
+
+	return c.sleepFor(r, d)
+}
+
+// stop returns error for step, stopped by fatal error.
+func (c *Config) stop(r *run, err error) error {
+	ferr := &FatalError{Name: r.name, Err: r.final(err), Attempt: r.attempts}
+
+	if c.onFatal != nil {
+		c.onFatal(r.name, r.attempts, ferr)
+	}
+
+	return ferr
+}
+
+// squeeze shortens delay `d` to fit into time left till deadline and elapsed time limit,
+// so one more attempt can be made, returns `TimeoutError`, if no time left.
+func (c *Config) squeeze(r *run, d time.Duration) (rv time.Duration, err error) {
+	if !c.deadline.IsZero() {
+		left := time.Until(c.deadline)
+		if left <= 0 {
+			return 0, &TimeoutError{Err: errGiveUp}
+		}
+
+		d = min(d, left)
+	}
+
+	if c.maxElapsed > 0 {
+		left := c.maxElapsed - time.Since(r.start)
+		if left <= 0 {
+			return 0, &TimeoutError{Err: errGiveUp}
+		}
+
+		d = min(d, left)
+	}
+
+	return d, nil
+}
+
+// warmUp awaits before first attempt, same delay as before first re-try, squeezed to fit
+// deadline and elapsed time limit, if there is no time left - first attempt starts at once.
+func (c *Config) warmUp(r *run) (err error) {
+	d, serr := c.squeeze(r, c.stepDuration(1, 0))
+	if serr != nil {
+		d = minDuration
+	}
+
+	return c.sleepFor(r, d)
+}
+
+// final returns resulting error, with all collected errors joined, if any, `last` replaces
+// error of last attempt, as it may carry additional information.
+func (r *run) final(last error) error {
+	if len(r.errs) < two {
+		return last
+	}
+
+	n := len(r.errs) - 1
+
+	return errors.Join(append(r.errs[:n:n], last)...)
+}
+
+func (r *run) report() Report {
+	return Report{Attempts: r.attempts, TotalSleep: r.slept, LastDelay: r.prev}
+}
+
+// cancel marks loop as ended by context cancellation, returning its cause.
+func (r *run) cancel() error {
+	r.canceled = true
+
+	return fmt.Errorf("%s: %w", r.name, context.Cause(r.ctx))
+}
+
+// waited records planned delay before next attempt, and time actually slept.
+func (r *run) waited(d, slept time.Duration) {
+	r.prev, r.longest = d, max(r.longest, slept)
+	r.slept += slept
+}
+
+// sleepFor waits for `d` before next attempt of `r`, recording time actually slept.
+func (c *Config) sleepFor(r *run, d time.Duration) (err error) {
+	slept, err := c.wait(r.ctx, d)
+
+	r.waited(d, slept)
+
+	return err
+}
+
+// wait sleeps for `d`, returns early with error, if `ctx` is done or drain signal arrives,
+// along with time actually slept.
+func (c *Config) wait(ctx context.Context, d time.Duration) (slept time.Duration, err error) {
+	start := time.Now()
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return min(time.Since(start), d), context.Cause(ctx)
+	case <-c.drain:
+		return min(time.Since(start), d), ErrDraining
+	case <-t.C:
+		return d, nil
+	}
+}
+
+// call runs single attempt, converting panics to errors, if requested.
+func (c *Config) call(ctx context.Context, fn func(context.Context) error) (err error) {
+	if c.recover {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("%w: %v", ErrPanic, r)
+			}
+		}()
+	}
+
+	return fn(ctx)
+}
@@ -0,0 +1,31 @@
+package retry
+
+import "sync"
+
+// Memoize wraps `fn`, so its first successful result is cached and returned on every
+// subsequent call, errors are not cached - `fn` will be called again until it succeeds.
+// Useful for expensive, idempotent parts of composite operations being re-tried.
+func Memoize[T any](fn func() (T, error)) func() (T, error) {
+	var (
+		mu   sync.Mutex
+		done bool
+		val  T
+	)
+
+	return func() (rv T, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if done {
+			return val, nil
+		}
+
+		if rv, err = fn(); err != nil {
+			return rv, err
+		}
+
+		val, done = rv, true
+
+		return val, nil
+	}
+}
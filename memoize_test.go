@@ -0,0 +1,84 @@
+package retry_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/s0rg/retry"
+)
+
+func TestMemoize(t *testing.T) {
+	t.Parallel()
+
+	const value = 42
+
+	var inner, outer int
+
+	part := retry.Memoize(func() (int, error) {
+		inner++
+
+		return value, nil
+	})
+
+	try := retry.New(
+		retry.Count(maxRetries),
+		retry.Sleep(time.Millisecond),
+	)
+
+	err := try.Single("test-memoize", func() error {
+		outer++
+
+		v, err := part()
+		if err != nil {
+			return err
+		}
+
+		if v != value {
+			t.Fatalf("value = %d (want: %d)", v, value)
+		}
+
+		return errFail
+	})
+	if err == nil {
+		t.Fatal("no error")
+	}
+
+	if outer != maxTries {
+		t.Fatalf("outer = %d (want: %d)", outer, maxTries)
+	}
+
+	if inner != 1 {
+		t.Fatalf("inner = %d (want: 1)", inner)
+	}
+}
+
+func TestMemoizeError(t *testing.T) {
+	t.Parallel()
+
+	var calls int
+
+	part := retry.Memoize(func() (int, error) {
+		calls++
+
+		if calls < 2 {
+			return 0, errFail
+		}
+
+		return calls, nil
+	})
+
+	for i := 0; i < maxTries; i++ {
+		v, err := part()
+
+		switch {
+		case i == 0 && err == nil:
+			t.Fatal("no error")
+		case i > 0 && v != 2:
+			t.Fatalf("step %d: value = %d", i, v)
+		}
+	}
+
+	if calls != 2 {
+		t.Fatalf("calls = %d (want: 2)", calls)
+	}
+}
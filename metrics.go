@@ -0,0 +1,8 @@
+package retry
+
+// Metrics receives retry statistics, i.e. to bridge them to histograms.
+type Metrics interface {
+	// ObserveAttempts is called once per retry loop on its completion,
+	// with number of attempts made.
+	ObserveAttempts(name string, attempts int)
+}
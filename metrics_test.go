@@ -0,0 +1,56 @@
+package retry_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/s0rg/retry"
+)
+
+type fakeMetrics struct {
+	attempts map[string]int
+}
+
+func (m *fakeMetrics) ObserveAttempts(name string, attempts int) {
+	m.attempts[name] = attempts
+}
+
+func TestMetrics(t *testing.T) {
+	t.Parallel()
+
+	m := &fakeMetrics{attempts: make(map[string]int)}
+
+	try := retry.New(
+		retry.Count(maxRetries),
+		retry.Sleep(time.Millisecond),
+		retry.WithMetrics(m),
+	)
+
+	fail := newFailer(errFail, func() {})
+
+	var table = []struct {
+		name     string
+		errCount int
+		attempts int
+	}{
+		{name: "metrics-first", errCount: 0, attempts: 1},
+		{name: "metrics-second", errCount: 1, attempts: 2},
+		{name: "metrics-exhausted", errCount: maxTries, attempts: maxTries},
+	}
+
+	for _, s := range table {
+		fail.Reset(s.errCount)
+
+		_ = try.Single(s.name, fail.Fail)
+	}
+
+	if len(m.attempts) != len(table) {
+		t.Fatalf("observed = %d (want: %d)", len(m.attempts), len(table))
+	}
+
+	for n, s := range table {
+		if got := m.attempts[s.name]; got != s.attempts {
+			t.Fatalf("step %d: attempts = %d (want: %d)", n, got, s.attempts)
+		}
+	}
+}
@@ -0,0 +1,21 @@
+package retry
+
+import (
+	"errors"
+	"net"
+)
+
+// RetryTemporary returns predicate, that reports true for errors, implementing `net.Error`
+// and reporting themselves as temporary or timeout ones - such errors worth re-trying,
+// use it with `RetryIf`.
+func RetryTemporary() func(error) bool {
+	return func(err error) bool {
+		var nerr net.Error
+
+		if !errors.As(err, &nerr) {
+			return false
+		}
+
+		return nerr.Timeout() || nerr.Temporary() //nolint:staticcheck // still useful for custom errors
+	}
+}
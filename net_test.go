@@ -0,0 +1,79 @@
+package retry_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/s0rg/retry"
+)
+
+type fakeNetError struct {
+	timeout   bool
+	temporary bool
+}
+
+func (e *fakeNetError) Error() string   { return "fake net error" }
+func (e *fakeNetError) Timeout() bool   { return e.timeout }
+func (e *fakeNetError) Temporary() bool { return e.temporary }
+
+func TestRetryTemporary(t *testing.T) {
+	t.Parallel()
+
+	var table = []struct {
+		err  error
+		want bool
+	}{
+		{err: &fakeNetError{temporary: true}, want: true},
+		{err: &fakeNetError{timeout: true}, want: true},
+		{err: fmt.Errorf("wrapped: %w", &fakeNetError{timeout: true}), want: true},
+		{err: &fakeNetError{}, want: false},
+		{err: errFail, want: false},
+		{err: nil, want: false},
+	}
+
+	check := retry.RetryTemporary()
+
+	for n, s := range table {
+		if got := check(s.err); got != s.want {
+			t.Fatalf("step %d: got %t (want: %t)", n, got, s.want)
+		}
+	}
+}
+
+func TestRetryTemporaryLoop(t *testing.T) {
+	t.Parallel()
+
+	try := retry.New(
+		retry.Count(maxRetries),
+		retry.Sleep(time.Millisecond),
+		retry.RetryIf(retry.RetryTemporary()),
+	)
+
+	var table = []struct {
+		err   error
+		count int
+	}{
+		{err: &fakeNetError{temporary: true}, count: maxTries},
+		{err: &fakeNetError{timeout: true}, count: maxTries},
+		{err: &fakeNetError{}, count: 1},
+	}
+
+	for n, s := range table {
+		var count int
+
+		err := try.Single("test-temporary", func() error {
+			count++
+
+			return s.err
+		})
+		if !errors.Is(err, s.err) {
+			t.Fatalf("step %d: err == %v", n, err)
+		}
+
+		if count != s.count {
+			t.Fatalf("step %d: count = %d (want: %d)", n, count, s.count)
+		}
+	}
+}
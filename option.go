@@ -1,16 +1,29 @@
 package retry
 
-import "time"
+import (
+	"hash/fnv"
+	"io"
+	"log/slog"
+	"time"
+)
 
 type option func(*Config)
 
-// Count sets number of retry attempts.
+// Count sets number of retries, so `fn` is executed at most n+1 times: zero (default) and
+// negative values mean single attempt, without retries. Note: earlier versions treated `n`
+// as number of attempts, use `Count(n-1)` to keep old behavior.
 func Count(n int) func(*Config) {
 	return func(s *Config) {
-		s.count = n
+		s.count = attempts(n)
 	}
 }
 
+// NoRetry makes every call single attempt, same as `Count(0)` (and `Count(1)` in earlier
+// versions, where `Count` set number of attempts).
+func NoRetry() func(*Config) {
+	return Count(0)
+}
+
 // Sleep sets sleep time between attempts.
 func Sleep(d time.Duration) func(*Config) {
 	return func(c *Config) {
@@ -26,6 +39,110 @@ func Jitter(d time.Duration) func(*Config) {
 	}
 }
 
+// MaxDelay sets upper limit for single delay between attempts (jitter included),
+// zero (default) - indicates no limit.
+func MaxDelay(d time.Duration) func(*Config) {
+	return func(c *Config) {
+		c.maxDelay = d
+	}
+}
+
+// MinDelay sets lower limit for single delay between attempts (jitter included),
+// zero (default) - indicates no limit. It never exceeds `MaxDelay`.
+func MinDelay(d time.Duration) func(*Config) {
+	return func(c *Config) {
+		c.minDelay = d
+	}
+}
+
+// EnvelopeBackoff sets capped, floored, exponentially growing and fully jittered backoff in one
+// call: delay is uniformly random in [0, base*mult^attempt), then kept in [min, max].
+func EnvelopeBackoff(base, minDelay, maxDelay time.Duration, mult float64) func(*Config) {
+	return func(c *Config) {
+		c.mode = FullJitter
+		c.sleep, c.base = base, mult
+		c.minDelay, c.maxDelay = minDelay, maxDelay
+	}
+}
+
+// MaxElapsedTime sets wall-clock budget for whole call (sleeps included): sleep, that goes past
+// the budget, is shortened to fit it, once budget is spent, call gives up with last error,
+// wrapped in `TimeoutError`.
+// Composes with `Count`, whichever limit is hit first wins. Zero (default) - indicates no limit.
+func MaxElapsedTime(d time.Duration) func(*Config) {
+	return func(c *Config) {
+		c.maxElapsed = d
+	}
+}
+
+// RandomJitter sets random jitter: every delay is increased by uniformly random value
+// in [0, d), to spread retries of different clients over time.
+func RandomJitter(d time.Duration) func(*Config) {
+	return func(c *Config) {
+		c.randJitter = d
+	}
+}
+
+// JitterDown turns `Jitter` into random decrease: every delay becomes `delay - rand[0, jitter)`,
+// not below zero, so configured delay is a ceiling, not a floor. For symmetric variant,
+// see `SpreadJitter`.
+func JitterDown(v bool) func(*Config) {
+	return func(c *Config) {
+		c.jitterDown = v
+	}
+}
+
+// JitterFraction sets relative random jitter: every delay is increased by uniformly random
+// value in [0, delay*f). Takes precedence over `Jitter`, which is ignored, if both set.
+func JitterFraction(f float64) func(*Config) {
+	return func(c *Config) {
+		c.jitterFrac = f
+	}
+}
+
+// SpreadJitter sets symmetric random jitter: every delay is moved by uniformly random value
+// in [-delay*f, delay*f), so some calls retry earlier, and some later, delays never go below zero.
+func SpreadJitter(f float64) func(*Config) {
+	return func(c *Config) {
+		c.spread = f
+	}
+}
+
+// Seed sets seed for random source, used by randomized features, for reproducible
+// delays. If not set, source is seeded randomly.
+func Seed(seed int64) func(*Config) {
+	return func(c *Config) {
+		c.rnd = newSource(uint64(seed)) //nolint:gosec // seed is just a bit pattern
+	}
+}
+
+// SeedKey seeds random source from hash of `key`, meant for per-call use with `SingleWith`,
+// so same logical operation always gets same delays.
+func SeedKey(key string) func(*Config) {
+	return func(c *Config) {
+		h := fnv.New64a()
+		_, _ = h.Write([]byte(key))
+
+		c.rnd = newSource(h.Sum64())
+	}
+}
+
+// CollectErrors makes failed calls return errors of all attempts joined, instead of last one.
+func CollectErrors(v bool) func(*Config) {
+	return func(c *Config) {
+		c.collect = v
+	}
+}
+
+// DelayFirst makes every call to wait before first attempt, initial delay equals to delay
+// before first re-try (attempt 1 for `BackoffFunc`), it counts towards `MaxElapsedTime` and
+// limited by it and `Deadline`.
+func DelayFirst(v bool) func(*Config) {
+	return func(c *Config) {
+		c.delayFirst = v
+	}
+}
+
 // Verbose sets verbosity of retry process.
 func Verbose(v bool) func(*Config) {
 	return func(c *Config) {
@@ -33,6 +150,14 @@ func Verbose(v bool) func(*Config) {
 	}
 }
 
+// LogSchedule enables logging of planned schedule once, before first attempt,
+// works only with `Verbose` enabled.
+func LogSchedule(v bool) func(*Config) {
+	return func(c *Config) {
+		c.logSchedule = v
+	}
+}
+
 // Parallelism sets max parallelism count, zero (default) - indicates no limit.
 func Parallelism(n int) func(*Config) {
 	return func(c *Config) {
@@ -40,7 +165,51 @@ func Parallelism(n int) func(*Config) {
 	}
 }
 
-// Mode sets sleep mode - linear, exponential or simple (by default).
+// SequentialBelow makes `Parallel` run steps one by one, when there are fewer than `n`
+// of them, to avoid goroutines overhead for tiny batches. Semantics stay the same: all steps
+// run and first error is returned (`ParallelCtx` stops the rest on it), `SharedLimit` and
+// `OnSharedFailure` still apply.
+func SequentialBelow(n int) func(*Config) {
+	return func(c *Config) {
+		c.seqBelow = n
+	}
+}
+
+// SharedLimit makes `Parallelism` limit config-wide, i.e. shared across all concurrent
+// `Parallel` calls, instead of per-call one.
+func SharedLimit(v bool) func(*Config) {
+	return func(c *Config) {
+		c.shared = v
+	}
+}
+
+// Base sets multiplier for exponential and full-jitter modes: delay grows as sleep*base^attempt,
+// values not above 1.0 are replaced with default 2.0.
+func Base(multiplier float64) func(*Config) {
+	return func(c *Config) {
+		c.base = multiplier
+	}
+}
+
+// BackoffFunc sets custom backoff function, returning delay before given (1-based) attempt,
+// it takes precedence over `Mode`, while `Jitter`, `RandomJitter` and `MaxDelay` still apply.
+func BackoffFunc(fn func(attempt int) time.Duration) func(*Config) {
+	return func(c *Config) {
+		c.backoff = fn
+	}
+}
+
+// DelayFromError sets function, that extracts delay from error of failed attempt (i.e.
+// from `Retry-After` header), if it reports success, its delay is used instead of computed
+// one, still limited by `MaxDelay`.
+func DelayFromError(fn func(error) (time.Duration, bool)) func(*Config) {
+	return func(c *Config) {
+		c.delayFrom = fn
+	}
+}
+
+// Mode sets sleep mode - linear, exponential, fibonacci, full-jitter, decorrelated, constant
+// or simple (by default). Unknown modes fall back to simple, with warning logged.
 func Mode(m mode) func(*Config) {
 	return func(c *Config) {
 		c.mode = m
@@ -53,3 +222,253 @@ func Fatal(errs ...error) func(*Config) {
 		c.fatal = append(c.fatal, errs...)
 	}
 }
+
+// FatalIf adds predicate for fatal errors, in addition to `Fatal` list: if it returns true,
+// for error of any attempt, call stops without further retries. Several predicates are merged,
+// like `Fatal` errors, so per-call ones (see `SingleWith`) extend configured.
+func FatalIf(fn func(error) bool) func(*Config) {
+	return func(c *Config) {
+		prev := c.fatalIf
+
+		c.fatalIf = func(err error) bool {
+			return (prev != nil && prev(err)) || fn(err)
+		}
+	}
+}
+
+// RetryBudget sets token-based limit for retries, shared across all calls on this config:
+// every retry consumes a token, and up to `tokens` are available per `per` time window.
+// When budget is exhausted, calls give up immediately, instead of retrying, with last error
+// wrapped in `ErrBudgetExhausted`. Non-positive
+// `tokens` or `per` are ignored (with warning logged). Budget is shared with `With` copies.
+func RetryBudget(tokens int, per time.Duration) func(*Config) {
+	return func(c *Config) {
+		c.budget = newBucket(tokens, per)
+	}
+}
+
+// OnGiveUp sets callback, that will be called once, when step gives up retrying (all attempts,
+// deadline or budget are exhausted), with number of attempts made and resulting error.
+// It is not called on success, fatal errors or context cancellation.
+func OnGiveUp(fn func(name string, attempts int, err error)) func(*Config) {
+	return func(c *Config) {
+		c.onGiveUp = fn
+	}
+}
+
+// OnFatal sets callback, that will be called once, when step stops early on fatal error
+// (see `Fatal`, `FatalIf`, `Permanent` and `RetryIf`), with number of attempt, that failed and
+// resulting error. Only one of `OnFatal` and `OnGiveUp` is called per call.
+func OnFatal(fn func(name string, attempt int, err error)) func(*Config) {
+	return func(c *Config) {
+		c.onFatal = fn
+	}
+}
+
+// OnCancel sets callback, that will be called once, when context cancellation ends the loop,
+// i.e. to run compensating logic. It is not called on success, fatal errors or exhaustion.
+func OnCancel(fn func()) func(*Config) {
+	return func(c *Config) {
+		c.onCancel = fn
+	}
+}
+
+// OnRecovery sets callback, that will be called when step succeeds after
+// at least one failed attempt, with number of failures before success.
+func OnRecovery(fn func(name string, afterFailures int)) func(*Config) {
+	return func(c *Config) {
+		c.onRecovery = fn
+	}
+}
+
+// MaxDistinctErrors sets limit for distinct error messages seen during single call,
+// when more than `n` are seen - call gives up, as constantly changing errors usually
+// indicate deeper problem. Zero (default) - indicates no limit, values not below
+// `MaxErrorHistory` are capped to `MaxErrorHistory`-1.
+func MaxDistinctErrors(n int) func(*Config) {
+	return func(c *Config) {
+		c.distinct = n
+	}
+}
+
+// Deadline sets absolute point in time, after which no attempts will be made: sleep, that
+// goes past `t`, is shortened to end at `t`, once it is passed, loop gives up with last error,
+// wrapped in `TimeoutError`.
+// If `t` is already passed, `fn` runs exactly once.
+func Deadline(t time.Time) func(*Config) {
+	return func(c *Config) {
+		c.deadline = t
+	}
+}
+
+// WarnAboveAttempts sets threshold for successful calls, which used more than `n` attempts
+// to succeed - such calls will be logged as warnings. Zero (default) - disables warnings.
+func WarnAboveAttempts(n int) func(*Config) {
+	return func(c *Config) {
+		c.warnAbove = n
+	}
+}
+
+// Classify sets error classifier, its result will be stored as `Category`
+// in `ExhaustedError` returned when step gives up.
+func Classify(fn func(err error) string) func(*Config) {
+	return func(c *Config) {
+		c.classify = fn
+	}
+}
+
+// YieldBetween sets channel to notify between attempts, allowing external scheduler
+// to follow retry process. Sends are non-blocking - if channel is not ready, notification
+// is dropped, so use buffered channel to not miss any.
+func YieldBetween(ch chan<- struct{}) func(*Config) {
+	return func(c *Config) {
+		c.yield = ch
+	}
+}
+
+// CountFunc sets function, that will be evaluated at start of every call, to get number of
+// retries for it, i.e. to shrink retry budget under high load. Overrides `Count`, if set.
+func CountFunc(fn func() int) func(*Config) {
+	return func(c *Config) {
+		c.countFn = fn
+	}
+}
+
+// AuditSink sets callback, that receives exactly one `AuditRecord` per retry loop,
+// summarizing its outcome, suitable for audit trails.
+func AuditSink(fn func(AuditRecord)) func(*Config) {
+	return func(c *Config) {
+		c.audit = fn
+	}
+}
+
+// SuccessIf sets predicate, that turns acceptable errors into success: if it returns true,
+// call stops and returns nil. It is evaluated before `Fatal` errors and `RetryIf`.
+func SuccessIf(fn func(error) bool) func(*Config) {
+	return func(c *Config) {
+		c.successIf = fn
+	}
+}
+
+// RetryIf sets predicate for retryable errors: if it returns false, call stops immediately
+// with that error. `Fatal` errors are checked first and always stop, regardless of it, errors
+// marked by `Retryable` are retried, without consulting it.
+func RetryIf(fn func(err error) bool) func(*Config) {
+	return func(c *Config) {
+		c.retryIf = fn
+	}
+}
+
+// FreeRetryIf sets predicate for errors, that are retried without consuming attempts,
+// i.e. errors clearly not caused by operation itself. Number of such retries per call
+// is limited by `MaxFreeRetries`.
+func FreeRetryIf(fn func(err error) bool) func(*Config) {
+	return func(c *Config) {
+		c.freeIf = fn
+	}
+}
+
+// MaxFreeRetries sets limit for free retries per call, if not set - number of attempts is used.
+func MaxFreeRetries(n int) func(*Config) {
+	return func(c *Config) {
+		c.maxFree = n
+	}
+}
+
+// WithLogger sets logger for verbose output, standard `log` is used by default.
+func WithLogger(l Logger) func(*Config) {
+	return func(c *Config) {
+		c.logger = l
+	}
+}
+
+// WithSlog sets structured logger, every failed attempt is reported at debug level,
+// failed step - at warn level, it replaces verbose output.
+func WithSlog(l *slog.Logger) func(*Config) {
+	return func(c *Config) {
+		c.slog = l
+	}
+}
+
+// WithMetrics sets metrics receiver.
+func WithMetrics(m Metrics) func(*Config) {
+	return func(c *Config) {
+		c.metrics = m
+	}
+}
+
+// CircuitOpen sets predicate, consulted before first attempt of every call, if it returns
+// true - call fails fast with `ErrCircuitOpen`, without calling step function.
+func CircuitOpen(fn func() bool) func(*Config) {
+	return func(c *Config) {
+		c.circuit = fn
+	}
+}
+
+// RecoverPanics enables recovery of panics in step functions: panic is converted to
+// error wrapping `ErrPanic` and handled as any other error (i.e. may be retried or
+// declared fatal). Panic always takes precedence over result, so panic raised from
+// deferred call, after function returned nil, still counts as failed attempt.
+func RecoverPanics(v bool) func(*Config) {
+	return func(c *Config) {
+		c.recover = v
+	}
+}
+
+// Freshness sets time limit, after which result of call considered useless: if elapsed time
+// exceeds `d`, loop is aborted (even in the middle of backoff) with `ErrStale`.
+func Freshness(d time.Duration) func(*Config) {
+	return func(c *Config) {
+		c.freshness = d
+	}
+}
+
+// NameFormat sets formatter, used to compose names of nested steps (i.e. chain or
+// parallel group and step) in errors and log lines, default is "parent: step".
+func NameFormat(fn func(parent, step string) string) func(*Config) {
+	return func(c *Config) {
+		c.nameFormat = fn
+	}
+}
+
+// OnSharedFailure sets hook (i.e. reconnect to shared resource), that will be run once per
+// `Parallel` call, when first of its steps fails, steps failing meanwhile will await for
+// hook to complete before next attempt.
+func OnSharedFailure(fn func() error) func(*Config) {
+	return func(c *Config) {
+		c.onShared = fn
+	}
+}
+
+// BudgetRemaining sets function, reporting remaining request budget, tracked elsewhere,
+// it is consulted before each attempt, and once it returns zero or less - call gives up
+// with `ErrBudgetExhausted`.
+func BudgetRemaining(fn func() int) func(*Config) {
+	return func(c *Config) {
+		c.remaining = fn
+	}
+}
+
+// EventLog sets writer for lifecycle events (start, attempt, backoff, success and giveup),
+// events are written as newline-delimited json, every line has `type` field.
+func EventLog(w io.Writer) func(*Config) {
+	return func(c *Config) {
+		c.events = newEventLog(w)
+	}
+}
+
+// DrainSignal sets channel, closing it makes in-flight loops finish their current attempt
+// and return without starting new ones: with nil on success, or `ErrDraining` otherwise.
+func DrainSignal(ch <-chan struct{}) func(*Config) {
+	return func(c *Config) {
+		c.drain = ch
+	}
+}
+
+// OnStart sets callback, that will be called once at start of every call, with worst-case
+// total time to be spent in backoff and maximal number of attempts.
+func OnStart(fn func(name string, maxExpected time.Duration, maxAttempts int)) func(*Config) {
+	return func(c *Config) {
+		c.onStart = fn
+	}
+}
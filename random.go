@@ -0,0 +1,29 @@
+package retry
+
+import (
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// source is goroutine-safe random source.
+type source struct {
+	rnd *rand.Rand
+	mu  sync.Mutex
+}
+
+func newSource(seed uint64) *source {
+	return &source{rnd: rand.New(rand.NewPCG(seed, seed))} //nolint:gosec // jitter is not security-sensitive
+}
+
+// duration returns random duration in [0, d).
+func (s *source) duration(d time.Duration) time.Duration {
+	if d <= 0 {
+		return 0
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return time.Duration(s.rnd.Int64N(int64(d)))
+}
@@ -0,0 +1,581 @@
+package retry_test
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/s0rg/retry"
+)
+
+func TestRandomJitter(t *testing.T) {
+	t.Parallel()
+
+	const (
+		seed   = 42
+		sleep  = 10 * time.Millisecond
+		jitter = 5 * time.Millisecond
+		draws  = 100
+	)
+
+	newTry := func() *retry.Config {
+		return retry.New(
+			retry.Sleep(sleep),
+			retry.RandomJitter(jitter),
+			retry.Seed(seed),
+		)
+	}
+
+	a, b := newTry(), newTry()
+
+	var distinct = make(map[time.Duration]struct{})
+
+	for n := 1; n <= draws; n++ {
+		da, db := a.StepDuration(n), b.StepDuration(n)
+
+		if da != db {
+			t.Fatalf("step %d: not reproducible: %s != %s", n, da, db)
+		}
+
+		if da < sleep || da >= sleep+jitter {
+			t.Fatalf("step %d: delay %s out of range", n, da)
+		}
+
+		distinct[da] = struct{}{}
+	}
+
+	if len(distinct) < 2 {
+		t.Fatal("delays are not random")
+	}
+}
+
+func TestJitterDown(t *testing.T) {
+	t.Parallel()
+
+	const (
+		sleep  = 100 * time.Millisecond
+		jitter = 40 * time.Millisecond
+		draws  = 100
+	)
+
+	try := retry.New(
+		retry.Sleep(sleep),
+		retry.Jitter(jitter),
+		retry.JitterDown(true),
+		retry.Seed(42),
+	)
+
+	var distinct = make(map[time.Duration]struct{})
+
+	for n := 1; n <= draws; n++ {
+		d := try.StepDuration(n)
+		if d < sleep-jitter || d >= sleep {
+			t.Fatalf("step %d: delay %s out of [%s, %s)", n, d, sleep-jitter, sleep)
+		}
+
+		distinct[d] = struct{}{}
+	}
+
+	if len(distinct) < 2 {
+		t.Fatal("delays are not random")
+	}
+
+	if d := try.With(retry.JitterDown(false)).StepDuration(1); d != sleep+jitter {
+		t.Fatalf("additive jitter: %s (want: %s)", d, sleep+jitter)
+	}
+}
+
+func TestSeedKey(t *testing.T) {
+	t.Parallel()
+
+	try := retry.New(
+		retry.Count(4),
+		retry.Sleep(time.Millisecond),
+		retry.RandomJitter(time.Millisecond),
+	)
+
+	delays := func(key string) (rv []time.Duration) {
+		_ = try.SingleWith("seed-key", func() error { return errFail },
+			retry.SeedKey(key),
+			retry.Inspect(func(s retry.State) {
+				rv = append(rv, s.Delay)
+			}),
+		)
+
+		return rv
+	}
+
+	a, b, c := delays("op-A"), delays("op-A"), delays("op-B")
+
+	if !slices.Equal(a, b) {
+		t.Fatalf("same key, different delays: %v != %v", a, b)
+	}
+
+	if slices.Equal(a, c) {
+		t.Fatalf("different keys, same delays: %v", a)
+	}
+}
+
+func TestRandomJitterParallel(t *testing.T) {
+	t.Parallel()
+
+	const (
+		sleep  = time.Millisecond
+		jitter = time.Millisecond
+		count  = 10
+	)
+
+	var (
+		mu     sync.Mutex
+		delays = make(map[time.Duration]int)
+	)
+
+	try := retry.New(
+		retry.Count(maxRetries),
+		retry.Sleep(sleep),
+		retry.RandomJitter(jitter),
+		retry.Inspect(func(s retry.State) {
+			if s.Attempt == 1 {
+				return
+			}
+
+			if s.Delay < sleep || s.Delay >= sleep+jitter {
+				t.Errorf("attempt %d: delay %s out of [%s, %s)", s.Attempt, s.Delay, sleep, sleep+jitter)
+			}
+
+			mu.Lock()
+			delays[s.Delay]++
+			mu.Unlock()
+		}),
+	)
+
+	steps := make([]retry.Step, count)
+	for i := range steps {
+		steps[i] = retry.Step{Name: fmt.Sprintf("jitter-%d", i), Func: func() error { return errFail }}
+	}
+
+	if err := try.Parallel(steps...); err == nil {
+		t.Fatal("no error")
+	}
+
+	var total int
+
+	for _, n := range delays {
+		total += n
+	}
+
+	if want := count * maxRetries; total != want {
+		t.Fatalf("delays = %d (want: %d)", total, want)
+	}
+
+	if len(delays) < 2 {
+		t.Fatal("delays are not random")
+	}
+}
+
+func TestFullJitter(t *testing.T) {
+	t.Parallel()
+
+	const (
+		sleep    = 10 * time.Millisecond
+		maxDelay = 100 * time.Millisecond
+		draws    = 1000
+	)
+
+	try := retry.New(
+		retry.Sleep(sleep),
+		retry.Jitter(time.Hour),
+		retry.MaxDelay(maxDelay),
+		retry.Mode(retry.FullJitter),
+		retry.Seed(1),
+	)
+
+	for n := 1; n <= 6; n++ {
+		upper := min(sleep*time.Duration(1<<n), maxDelay)
+
+		var top time.Duration
+
+		for i := 0; i < draws; i++ {
+			d := try.StepDuration(n)
+			if d < 0 || d >= upper {
+				t.Fatalf("attempt %d: delay %s out of [0, %s)", n, d, upper)
+			}
+
+			top = max(top, d)
+		}
+
+		if top < upper/2 {
+			t.Fatalf("attempt %d: draws are too low: %s", n, top)
+		}
+	}
+}
+
+func TestEnvelopeBackoff(t *testing.T) {
+	t.Parallel()
+
+	const (
+		base     = 10 * time.Millisecond
+		minDelay = 20 * time.Millisecond
+		maxDelay = 500 * time.Millisecond
+		mult     = 3.0
+		steps    = 8
+		draws    = 1000
+	)
+
+	try := retry.New(
+		retry.EnvelopeBackoff(base, minDelay, maxDelay, mult),
+		retry.Seed(3),
+	)
+
+	var prev time.Duration
+
+	for n := 1; n <= steps; n++ {
+		var sum, top time.Duration
+
+		for i := 0; i < draws; i++ {
+			d := try.StepDuration(n)
+			if d < minDelay || d > maxDelay {
+				t.Fatalf("attempt %d: delay %s out of [%s, %s]", n, d, minDelay, maxDelay)
+			}
+
+			sum += d
+			top = max(top, d)
+		}
+
+		// averages grow, until previous attempt already hits the cap.
+		avg := sum / draws
+		if uncapped := base * time.Duration(math.Pow(mult, float64(n-1))); uncapped < maxDelay && avg <= prev {
+			t.Fatalf("attempt %d: average %s not above previous %s", n, avg, prev)
+		}
+
+		prev = avg
+
+		if n == steps && top < maxDelay*9/10 {
+			t.Fatalf("attempt %d: draws do not reach cap: %s", n, top)
+		}
+	}
+}
+
+func TestMinDelay(t *testing.T) {
+	t.Parallel()
+
+	const (
+		floor = 5 * time.Millisecond
+		draws = 1000
+	)
+
+	try := retry.New(
+		retry.Sleep(time.Millisecond),
+		retry.Mode(retry.FullJitter),
+		retry.MinDelay(floor),
+		retry.Seed(5),
+	)
+
+	var hits int
+
+	for i := 0; i < draws; i++ {
+		d := try.StepDuration(1)
+		if d < floor {
+			t.Fatalf("draw %d: delay %s below %s", i, d, floor)
+		}
+
+		if d == floor {
+			hits++
+		}
+	}
+
+	if hits == 0 {
+		t.Fatal("floor is never hit")
+	}
+
+	if d := try.With(retry.Mode(retry.Constant)).StepDuration(1); d != floor {
+		t.Fatalf("constant: delay %s (want: %s)", d, floor)
+	}
+
+	// floor is clamped to MaxDelay.
+	capped := retry.New(
+		retry.Sleep(time.Millisecond),
+		retry.MinDelay(time.Second),
+		retry.MaxDelay(100*time.Millisecond),
+	)
+
+	if d, want := capped.StepDuration(1), 100*time.Millisecond; d != want {
+		t.Fatalf("capped: delay %s (want: %s)", d, want)
+	}
+
+	if d := capped.Describe(); !strings.Contains(d, "min=100ms") {
+		t.Fatalf("capped: describe = %q", d)
+	}
+
+	if d := try.With(retry.MinDelay(-time.Second)).StepDuration(1); d >= 4*time.Millisecond {
+		t.Fatalf("negative: delay %s (want: no floor)", d)
+	}
+}
+
+func TestDecorrelated(t *testing.T) {
+	t.Parallel()
+
+	const (
+		sleep    = 10 * time.Millisecond
+		maxDelay = time.Second
+		steps    = 8
+		runs     = 200
+	)
+
+	try := retry.New(
+		retry.Sleep(sleep),
+		retry.MaxDelay(maxDelay),
+		retry.Mode(retry.Decorrelated),
+		retry.Seed(7),
+	)
+
+	var sums [steps]time.Duration
+
+	for r := 0; r < runs; r++ {
+		var prev time.Duration
+
+		for n := 0; n < steps; n++ {
+			d := try.NextDuration(n+1, prev)
+			if d < sleep || d > maxDelay {
+				t.Fatalf("run %d: attempt %d: delay %s out of [%s, %s]", r, n+1, d, sleep, maxDelay)
+			}
+
+			if lim := 3 * max(prev, sleep); d >= lim {
+				t.Fatalf("run %d: attempt %d: delay %s above %s", r, n+1, d, lim)
+			}
+
+			sums[n] += d
+			prev = d
+		}
+	}
+
+	// mean delay grows roughly geometrically (x1.5 per step, on average), until capped.
+	for n := 1; n < 4; n++ {
+		if sums[n] <= sums[n-1] {
+			t.Fatalf("attempt %d: mean delay does not grow: %s <= %s", n+1, sums[n]/runs, sums[n-1]/runs)
+		}
+	}
+}
+
+func TestDecorrelatedParallel(t *testing.T) {
+	t.Parallel()
+
+	const (
+		sleep    = time.Millisecond
+		maxDelay = 5 * time.Millisecond
+		retries  = 3
+		count    = 4
+	)
+
+	var delays atomic.Int32
+
+	try := retry.New(
+		retry.Count(retries),
+		retry.Sleep(sleep),
+		retry.MaxDelay(maxDelay),
+		retry.Mode(retry.Decorrelated),
+		retry.Inspect(func(s retry.State) {
+			if s.Attempt == 1 {
+				return
+			}
+
+			if s.Delay < sleep || s.Delay > maxDelay {
+				t.Errorf("attempt %d: delay %s out of [%s, %s]", s.Attempt, s.Delay, sleep, maxDelay)
+			}
+
+			delays.Add(1)
+		}),
+	)
+
+	steps := make([]retry.Step, count)
+	for i := range steps {
+		steps[i] = retry.Step{Name: fmt.Sprintf("decorrelated-%d", i), Func: func() error { return errFail }}
+	}
+
+	if err := try.Parallel(steps...); err == nil {
+		t.Fatal("no error")
+	}
+
+	if n, want := delays.Load(), int32(count*retries); n != want {
+		t.Fatalf("delays = %d (want: %d)", n, want)
+	}
+}
+
+func TestJitterFraction(t *testing.T) {
+	t.Parallel()
+
+	const (
+		sleep = 10 * time.Millisecond
+		frac  = 0.5
+		draws = 100
+	)
+
+	try := retry.New(
+		retry.Sleep(sleep),
+		retry.Jitter(time.Hour),
+		retry.Mode(retry.Exponential),
+		retry.JitterFraction(frac),
+	)
+
+	var distinct = make(map[time.Duration]struct{})
+
+	for i := 0; i < draws; i++ {
+		n := 1 + i%5
+		base := sleep << n
+
+		d := try.StepDuration(n)
+		if d < base || d >= base+time.Duration(float64(base)*frac) {
+			t.Fatalf("step %d: delay %s out of range for %s", i, d, base)
+		}
+
+		distinct[d] = struct{}{}
+	}
+
+	if len(distinct) < 2 {
+		t.Fatal("delays are not random")
+	}
+
+	// fraction of tiny delay rounds to zero: nothing to draw.
+	tiny := try.With(retry.Sleep(time.Nanosecond), retry.Mode(retry.Constant))
+	if d := tiny.StepDuration(1); d != time.Nanosecond {
+		t.Fatalf("tiny: delay %s (want: 1ns)", d)
+	}
+
+	// huge fraction saturates, instead of overflow.
+	if d := try.With(retry.JitterFraction(math.MaxFloat64)).StepDuration(1); d < 2*sleep {
+		t.Fatalf("huge: delay %s (want: at least %s)", d, 2*sleep)
+	}
+}
+
+func TestSpreadJitter(t *testing.T) {
+	t.Parallel()
+
+	const (
+		sleep  = 100 * time.Millisecond
+		spread = 0.2
+		draws  = 2000
+	)
+
+	try := retry.New(
+		retry.Sleep(sleep),
+		retry.SpreadJitter(spread),
+		retry.Mode(retry.Constant),
+		retry.Seed(42),
+	)
+
+	var (
+		sum      time.Duration
+		low, top = sleep, sleep
+		lim      = time.Duration(float64(sleep) * spread)
+	)
+
+	for n := 1; n <= draws; n++ {
+		d := try.StepDuration(n)
+		if d < sleep-lim || d > sleep+lim {
+			t.Fatalf("step %d: delay %s out of range", n, d)
+		}
+
+		sum += d
+		low, top = min(low, d), max(top, d)
+	}
+
+	if mean := sum / draws; mean < sleep-lim/10 || mean > sleep+lim/10 {
+		t.Fatalf("mean = %s (want: ~%s)", mean, sleep)
+	}
+
+	if low > sleep-lim*9/10 || top < sleep+lim*9/10 {
+		t.Fatalf("spread = [%s, %s] (want: ~%s)", low, top, lim)
+	}
+
+	wide := retry.New(
+		retry.Sleep(sleep),
+		retry.SpreadJitter(3),
+		retry.Mode(retry.Constant),
+	)
+
+	for n := 1; n <= draws; n++ {
+		if d := wide.StepDuration(n); d < 0 {
+			t.Fatalf("step %d: negative delay %s", n, d)
+		}
+	}
+}
+
+func TestOnStartWorstCase(t *testing.T) {
+	t.Parallel()
+
+	const draws = 20
+
+	var tries = []func(*retry.Config){
+		retry.Mode(retry.FullJitter),
+		retry.Mode(retry.Decorrelated),
+		retry.RandomJitter(5 * time.Millisecond),
+		retry.JitterFraction(0.5),
+		retry.SpreadJitter(0.5),
+	}
+
+	for n, opt := range tries {
+		var (
+			worst  time.Duration
+			actual time.Duration
+		)
+
+		try := retry.New(
+			retry.Count(maxRetries),
+			retry.Sleep(time.Millisecond),
+			retry.MaxDelay(20*time.Millisecond),
+			opt,
+			retry.OnStart(func(_ string, d time.Duration, _ int) {
+				worst = d
+			}),
+			retry.Inspect(func(s retry.State) {
+				actual += s.Delay
+			}),
+		)
+
+		for i := 0; i < draws; i++ {
+			actual = 0
+
+			_ = try.Single("test-worst-case", func() error { return errFail })
+
+			if actual > worst {
+				t.Fatalf("step %d: actual %s > worst %s", n, actual, worst)
+			}
+
+			if sched := try.Schedule(); worst != sched[0]+sched[1] {
+				t.Fatalf("step %d: worst %s != schedule %v", n, worst, sched)
+			}
+		}
+	}
+}
+
+func TestSeedPreview(t *testing.T) {
+	t.Parallel()
+
+	const draws = 10
+
+	newTry := func() *retry.Config {
+		return retry.New(
+			retry.Count(draws),
+			retry.Sleep(10*time.Millisecond),
+			retry.Mode(retry.FullJitter),
+			retry.RandomJitter(5*time.Millisecond),
+			retry.Seed(42),
+		)
+	}
+
+	a, b := newTry(), newTry()
+
+	_ = b.Describe()
+	_ = b.Schedule()
+
+	for n := 1; n <= draws; n++ {
+		if da, db := a.StepDuration(n), b.StepDuration(n); da != db {
+			t.Fatalf("step %d: preview changed delays: %s != %s", n, da, db)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package retry
+
+import (
+	"context"
+	"slices"
+	"sync"
+)
+
+// Session runs calls using policy of parent `Config`, keeping its own state: errors of
+// failed calls and `Stats`, so parent config stays untouched and shareable. Session can be
+// canceled from another goroutine, see `Cancel`.
+type Session struct {
+	ctx    context.Context //nolint:containedctx // cancels all calls of session
+	cfg    *Config
+	cancel context.CancelCauseFunc
+	errs   []error
+	mu     sync.Mutex
+}
+
+// NewSession creates new session, with fresh state, `SharedLimit` stays shared with parent.
+func (c *Config) NewSession() *Session {
+	ctx, cancel := context.WithCancelCause(context.Background())
+
+	cfg := c.With()
+	cfg.sem = c.sem
+
+	return &Session{cfg: cfg, ctx: ctx, cancel: cancel}
+}
+
+// Cancel stops current calls of session, including their sleeps, they return `ErrCanceled`,
+// as well as all following calls.
+func (s *Session) Cancel() {
+	s.cancel(ErrCanceled)
+}
+
+// Single acts like `Config.Single`.
+func (s *Session) Single(name string, fn func() error) (err error) {
+	return s.record(s.cfg.SingleCtx(s.ctx, name, fn))
+}
+
+// Chain acts like `Config.Chain`.
+func (s *Session) Chain(steps ...Step) (err error) {
+	return s.record(s.cfg.ChainCtx(s.ctx, steps...))
+}
+
+// Parallel acts like `Config.Parallel`.
+func (s *Session) Parallel(steps ...Step) (err error) {
+	return s.record(s.cfg.fanOut(s.ctx, steps))
+}
+
+// Errors returns errors of all failed calls made in this session.
+func (s *Session) Errors() []error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return slices.Clone(s.errs)
+}
+
+// Stats returns snapshot of counters, collected in this session.
+func (s *Session) Stats() Stats {
+	return s.cfg.Stats()
+}
+
+func (s *Session) record(err error) error {
+	if err != nil {
+		s.mu.Lock()
+		s.errs = append(s.errs, err)
+		s.mu.Unlock()
+	}
+
+	return err
+}
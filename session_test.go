@@ -0,0 +1,151 @@
+package retry_test
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/s0rg/retry"
+)
+
+func TestSession(t *testing.T) {
+	t.Parallel()
+
+	try := retry.New(
+		retry.Count(maxRetries),
+		retry.Sleep(time.Millisecond),
+	)
+
+	a, b := try.NewSession(), try.NewSession()
+
+	fail := func() error { return errFail }
+	fatal := func() error { return errFatal }
+
+	_ = a.Single("session-a", fail)
+	_ = a.Chain(retry.Step{Name: "session-a-chain", Func: fatal})
+	_ = b.Parallel(retry.Step{Name: "session-b", Func: fatal})
+	_ = b.Single("session-b-ok", func() error { return nil })
+
+	if errs := a.Errors(); len(errs) != 2 || !errors.Is(errs[0], errFail) || !errors.Is(errs[1], errFatal) {
+		t.Fatalf("a: errors = %v", errs)
+	}
+
+	if errs := b.Errors(); len(errs) != 1 || !errors.Is(errs[0], errFatal) {
+		t.Fatalf("b: errors = %v", errs)
+	}
+
+	if n := a.Stats().Attempts; n != 2*maxTries {
+		t.Fatalf("a: attempts = %d (want: %d)", n, 2*maxTries)
+	}
+
+	if n := b.Stats().Attempts; n != maxTries+1 {
+		t.Fatalf("b: attempts = %d (want: %d)", n, maxTries+1)
+	}
+
+	if n := try.Stats().Attempts; n != 0 {
+		t.Fatalf("parent: attempts = %d (want: 0)", n)
+	}
+}
+
+func TestSessionSharedLimit(t *testing.T) {
+	t.Parallel()
+
+	try := retry.New(
+		retry.Parallelism(2),
+		retry.SharedLimit(true),
+	)
+
+	var (
+		active, peak atomic.Int32
+		wg           sync.WaitGroup
+	)
+
+	step := func(name string) retry.Step {
+		return retry.Step{Name: name, Func: func() error {
+			n := active.Add(1)
+			defer active.Add(-1)
+
+			for {
+				if p := peak.Load(); n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+
+			time.Sleep(10 * time.Millisecond)
+
+			return nil
+		}}
+	}
+
+	for i := 0; i < 3; i++ {
+		s := try.NewSession()
+
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			if err := s.Parallel(step("limit-A"), step("limit-B"), step("limit-C")); err != nil {
+				t.Errorf("session %d: err == %v", i, err)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if p := peak.Load(); p > 2 {
+		t.Fatalf("peak = %d (want: <= 2)", p)
+	}
+}
+
+func TestSessionCancel(t *testing.T) {
+	t.Parallel()
+
+	try := retry.New(
+		retry.Count(maxRetries),
+		retry.Sleep(time.Hour),
+	)
+
+	s := try.NewSession()
+
+	var count int
+
+	start := time.Now()
+
+	time.AfterFunc(10*time.Millisecond, s.Cancel)
+
+	err := s.Single("session-cancel", func() error {
+		count++
+
+		return errFail
+	})
+	if !errors.Is(err, retry.ErrCanceled) {
+		t.Fatalf("err == %v", err)
+	}
+
+	if took := time.Since(start); took > time.Second {
+		t.Fatalf("cancel took %s", took)
+	}
+
+	if count != 1 {
+		t.Fatalf("count = %d (want: 1)", count)
+	}
+
+	if err = s.Chain(retry.Step{Name: "session-after", Func: func() error {
+		count++
+
+		return nil
+	}}); !errors.Is(err, retry.ErrCanceled) {
+		t.Fatalf("err == %v", err)
+	}
+
+	if count != 1 {
+		t.Fatalf("count = %d (want: 1)", count)
+	}
+
+	if err = try.Single("parent", func() error { return nil }); err != nil {
+		t.Fatalf("parent: err == %v", err)
+	}
+}
@@ -0,0 +1,76 @@
+package retry_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/s0rg/retry"
+)
+
+func TestParallelSignal(t *testing.T) {
+	t.Parallel()
+
+	var (
+		got     []os.Signal
+		stopped bool
+	)
+
+	// synthetic signal: context is canceled by timer, instead of real signal delivery.
+	notify := func(ctx context.Context, sig ...os.Signal) (context.Context, context.CancelFunc) {
+		got = sig
+
+		ctx, cancel := context.WithCancel(ctx)
+		time.AfterFunc(20*time.Millisecond, cancel)
+
+		return ctx, func() {
+			stopped = true
+
+			cancel()
+		}
+	}
+
+	try := retry.New(
+		retry.Count(maxRetries),
+		retry.Sleep(time.Hour),
+	)
+
+	fail := func() error { return errFail }
+	start := time.Now()
+
+	err := try.ParallelNotify(notify, os.Interrupt, syscall.SIGTERM)(
+		retry.Step{Name: "signal-A", Func: fail},
+		retry.Step{Name: "signal-B", Func: fail},
+	)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("err == %v", err)
+	}
+
+	if took := time.Since(start); took > time.Second {
+		t.Fatalf("abort took %s", took)
+	}
+
+	if len(got) != 2 || got[0] != os.Interrupt || got[1] != syscall.SIGTERM {
+		t.Fatalf("signals = %v", got)
+	}
+
+	if !stopped {
+		t.Fatal("notify is not stopped")
+	}
+}
+
+func TestParallelSignalOK(t *testing.T) {
+	t.Parallel()
+
+	try := retry.New()
+
+	// no signals are sent: handler is just registered and stopped.
+	if err := try.ParallelSignal(syscall.SIGUSR2)(
+		retry.Step{Name: "signal-ok", Func: func() error { return nil }},
+	); err != nil {
+		t.Fatal(err)
+	}
+}
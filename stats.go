@@ -0,0 +1,61 @@
+package retry
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Stats is a snapshot of aggregated counters, collected over `Config` lifetime.
+type Stats struct {
+	// Attempts is a total number of calls made.
+	Attempts int64
+	// Retries is a total number of calls made after first one, in every loop.
+	Retries int64
+	// Exhausted is a number of loops gave up retrying.
+	Exhausted int64
+	// Sleep is a total time spent in backoff.
+	Sleep time.Duration
+}
+
+type counters struct {
+	attempts  atomic.Int64
+	retries   atomic.Int64
+	exhausted atomic.Int64
+	sleep     atomic.Int64
+}
+
+func (s *counters) observe(r *run, exhausted bool) {
+	s.attempts.Add(int64(r.attempts))
+	s.retries.Add(int64(max(r.attempts-1, 0)))
+	s.sleep.Add(int64(r.slept))
+
+	if exhausted {
+		s.exhausted.Add(1)
+	}
+}
+
+func (s *counters) snapshot() Stats {
+	return Stats{
+		Attempts:  s.attempts.Load(),
+		Retries:   s.retries.Load(),
+		Exhausted: s.exhausted.Load(),
+		Sleep:     time.Duration(s.sleep.Load()),
+	}
+}
+
+func (s *counters) reset() {
+	s.attempts.Store(0)
+	s.retries.Store(0)
+	s.exhausted.Store(0)
+	s.sleep.Store(0)
+}
+
+// Stats returns snapshot of counters, collected so far.
+func (c *Config) Stats() Stats {
+	return c.stats.snapshot()
+}
+
+// ResetStats zeroes all counters, i.e. to start new measurement window.
+func (c *Config) ResetStats() {
+	c.stats.reset()
+}
@@ -0,0 +1,152 @@
+package retry_test
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/s0rg/retry"
+)
+
+func TestStats(t *testing.T) {
+	t.Parallel()
+
+	const (
+		recovered = 50
+		failed    = 10
+	)
+
+	try := retry.New(
+		retry.Count(maxRetries),
+		retry.Sleep(time.Millisecond),
+	)
+
+	steps := make([]retry.Step, 0, recovered+failed)
+
+	for i := 0; i < recovered; i++ {
+		var calls atomic.Int32
+
+		steps = append(steps, retry.Step{Name: "ok-" + strconv.Itoa(i), Func: func() error {
+			if calls.Add(1) == 1 {
+				return errFail
+			}
+
+			return nil
+		}})
+	}
+
+	for i := 0; i < failed; i++ {
+		steps = append(steps, retry.Step{Name: "fail-" + strconv.Itoa(i), Func: func() error {
+			return errFail
+		}})
+	}
+
+	if err := try.ParallelAll(steps...); !errors.Is(err, errFail) {
+		t.Fatalf("err == %v", err)
+	}
+
+	retries := int64(recovered + failed*(maxTries-1))
+
+	want := retry.Stats{
+		Attempts:  int64(recovered*2 + failed*maxTries),
+		Retries:   retries,
+		Exhausted: failed,
+		Sleep:     time.Duration(retries) * time.Millisecond,
+	}
+
+	if got := try.Stats(); got != want {
+		t.Fatalf("stats = %+v (want: %+v)", got, want)
+	}
+
+	try.ResetStats()
+
+	if got := try.Stats(); got != (retry.Stats{}) {
+		t.Fatalf("stats = %+v (want: zero)", got)
+	}
+}
+
+func TestConcurrentSingle(t *testing.T) {
+	t.Parallel()
+
+	const calls = 500
+
+	try := retry.New(
+		retry.Count(maxRetries),
+		retry.Sleep(time.Millisecond),
+		retry.MaxDelay(5*time.Millisecond),
+		retry.Mode(retry.Decorrelated),
+		retry.RandomJitter(time.Millisecond),
+		retry.CollectErrors(true),
+		retry.Inspect(func(retry.State) {}),
+	)
+
+	var (
+		wg    sync.WaitGroup
+		fails atomic.Int32
+	)
+
+	wg.Add(calls)
+
+	for i := 0; i < calls; i++ {
+		go func() {
+			defer wg.Done()
+
+			var count int
+
+			err := try.Single("concurrent-"+strconv.Itoa(i), func() error {
+				if count++; count < maxTries || i%2 == 0 {
+					return errFail
+				}
+
+				return nil
+			})
+			if err != nil {
+				fails.Add(1)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if n := fails.Load(); n != calls/2 {
+		t.Fatalf("fails = %d (want: %d)", n, calls/2)
+	}
+
+	stats := try.Stats()
+
+	if stats.Attempts != calls*maxTries || stats.Exhausted != calls/2 {
+		t.Fatalf("stats = %+v", stats)
+	}
+}
+
+func TestStatsCanceledSleep(t *testing.T) {
+	t.Parallel()
+
+	var rec retry.AuditRecord
+
+	try := retry.New(
+		retry.Count(maxRetries),
+		retry.Sleep(time.Hour),
+		retry.AuditSink(func(r retry.AuditRecord) { rec = r }),
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+
+	err := try.SingleCtx(ctx, "canceled-sleep", func() error { return errFail })
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("err == %v", err)
+	}
+
+	if d := try.Stats().Sleep; d < 10*time.Millisecond || d > time.Second {
+		t.Fatalf("sleep = %s (want: time actually slept)", d)
+	}
+
+	if d := rec.MaxBackoff; d < 10*time.Millisecond || d > time.Second {
+		t.Fatalf("max backoff = %s (want: time actually slept)", d)
+	}
+}
@@ -0,0 +1,48 @@
+package retry
+
+import "context"
+
+// StepEvent represents chain progress notification.
+type StepEvent struct {
+	Err      error
+	Name     string
+	Attempts int
+	Done     bool
+}
+
+// ChainStream executes several `steps` one by one in background, emitting `StepEvent`
+// as each step starts and finishes, final chain error (if any) is sent to second channel.
+// Both channels are buffered, so stopping to read from them will not leak goroutine.
+func (c *Config) ChainStream(steps ...Step) (<-chan StepEvent, <-chan error) {
+	events := make(chan StepEvent, two*len(steps))
+	errc := make(chan error, 1)
+
+	go func() {
+		defer close(errc)
+		defer close(events)
+
+		var (
+			step *Step
+			err  error
+			rep  Report
+		)
+
+		for i := 0; i < len(steps); i++ {
+			step = &steps[i]
+
+			events <- StepEvent{Name: step.Name}
+
+			rep, err = c.forStep(step).single(context.Background(), c.compose("chain", step.Name), step.Func)
+
+			events <- StepEvent{Name: step.Name, Attempts: rep.Attempts, Err: err, Done: true}
+
+			if err != nil {
+				errc <- err
+
+				return
+			}
+		}
+	}()
+
+	return events, errc
+}
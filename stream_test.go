@@ -0,0 +1,94 @@
+package retry_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/s0rg/retry"
+)
+
+func TestChainStream(t *testing.T) {
+	t.Parallel()
+
+	var countA int
+
+	fb := newFailer(errFail, func() {})
+	fb.Reset(maxTries)
+
+	try := retry.New(
+		retry.Count(maxRetries),
+		retry.Sleep(time.Millisecond),
+	)
+
+	events, errc := try.ChainStream(
+		retry.Step{Name: "stream-A", Func: func() error {
+			if countA++; countA < 2 {
+				return errFail
+			}
+
+			return nil
+		}},
+		retry.Step{Name: "stream-B", Func: fb.Fail},
+		retry.Step{Name: "stream-C", Func: func() error { return nil }},
+	)
+
+	var got []retry.StepEvent
+
+	for e := range events {
+		got = append(got, e)
+	}
+
+	if err := <-errc; !errors.Is(err, errFail) {
+		t.Fatalf("err == %v", err)
+	}
+
+	want := []struct {
+		name     string
+		attempts int
+		done     bool
+		failed   bool
+	}{
+		{name: "stream-A"},
+		{name: "stream-A", attempts: 2, done: true},
+		{name: "stream-B"},
+		{name: "stream-B", attempts: maxTries, done: true, failed: true},
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("events = %d (want: %d)", len(got), len(want))
+	}
+
+	for i, w := range want {
+		e := got[i]
+
+		if e.Name != w.name || e.Attempts != w.attempts || e.Done != w.done || (e.Err != nil) != w.failed {
+			t.Fatalf("step %d: unexpected event: %+v", i, e)
+		}
+	}
+}
+
+func TestChainStreamAbandon(t *testing.T) {
+	t.Parallel()
+
+	try := retry.New(
+		retry.Sleep(time.Millisecond),
+	)
+
+	done := make(chan struct{})
+
+	_, errc := try.ChainStream(
+		retry.Step{Name: "abandon-A", Func: func() error { return nil }},
+		retry.Step{Name: "abandon-B", Func: func() error {
+			close(done)
+
+			return nil
+		}},
+	)
+
+	<-done
+
+	if err := <-errc; err != nil {
+		t.Fatal(err)
+	}
+}